@@ -0,0 +1,73 @@
+package askpass
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/xakep666/gkpxc/envrun"
+)
+
+// ErrNotResolved returned if prompt can't be answered neither from KeepassXC nor by fallback.
+var ErrNotResolved = errors.New("prompt not resolved")
+
+// FallbackFunc asks value from somewhere else (i.e. terminal). Echo is true when input may be shown.
+type FallbackFunc func(prompt string, echo bool) (string, error)
+
+// Asker answers prompts.
+type Asker struct {
+	// Resolver is used to take values from KeepassXC. If nil only Fallback used.
+	Resolver *envrun.Resolver
+
+	// Fallback is called if value can't be taken from KeepassXC. Optional.
+	Fallback FallbackFunc
+}
+
+// Ask parses prompt and answers it.
+func (a *Asker) Ask(ctx context.Context, prompt string) (string, error) {
+	q, ok := ParsePrompt(prompt)
+	if !ok {
+		return a.fallback(prompt, q, ErrNotResolved)
+	}
+
+	return a.AskQuery(ctx, prompt, q)
+}
+
+// AskQuery answers already parsed query. Prompt is shown on fallback.
+func (a *Asker) AskQuery(ctx context.Context, prompt string, q Query) (string, error) {
+	if a.Resolver == nil || q.Host == "" {
+		return a.fallback(prompt, q, ErrNotResolved)
+	}
+
+	value, err := a.Resolver.Resolve(ctx, q.reference())
+	if err != nil {
+		return a.fallback(prompt, q, err)
+	}
+
+	return value, nil
+}
+
+func (a *Asker) fallback(prompt string, q Query, cause error) (string, error) {
+	if a.Fallback == nil {
+		return "", fmt.Errorf("%q: %w", prompt, cause)
+	}
+
+	return a.Fallback(prompt, q.Kind == KindUsername)
+}
+
+func (q Query) reference() envrun.Reference {
+	ref := envrun.Reference{
+		URL:   q.URL(),
+		Login: q.User,
+		Field: envrun.FieldPassword,
+	}
+
+	switch q.Kind {
+	case KindUsername:
+		ref.Field = envrun.FieldLogin
+	case KindTOTP:
+		ref.Field = envrun.FieldTOTP
+	}
+
+	return ref
+}
@@ -0,0 +1,97 @@
+package askpass
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestParsePrompt(t *testing.T) {
+	hostname, _ := os.Hostname()
+
+	cases := []struct {
+		prompt string
+		expect Query
+		url    string
+	}{
+		{
+			prompt: "user@example.com's password: ",
+			expect: Query{Kind: KindPassword, Scheme: "ssh", User: "user", Host: "example.com"},
+			url:    "ssh://example.com",
+		},
+		{
+			prompt: "(user@example.com) Password: ",
+			expect: Query{Kind: KindPassword, Scheme: "ssh", User: "user", Host: "example.com"},
+			url:    "ssh://example.com",
+		},
+		{
+			prompt: "(user@example.com) Verification code: ",
+			expect: Query{Kind: KindTOTP, Scheme: "ssh", User: "user", Host: "example.com"},
+			url:    "ssh://example.com",
+		},
+		{
+			prompt: "Password for 'https://user@github.com': ",
+			expect: Query{Kind: KindPassword, Scheme: "https", User: "user", Host: "github.com"},
+			url:    "https://github.com",
+		},
+		{
+			prompt: "Username for 'https://github.com/org/repo': ",
+			expect: Query{Kind: KindUsername, Scheme: "https", Host: "github.com", Path: "/org/repo"},
+			url:    "https://github.com/org/repo",
+		},
+		{
+			prompt: "Password for admin@10.0.0.1: ",
+			expect: Query{Kind: KindPassword, Scheme: "ssh", User: "admin", Host: "10.0.0.1"},
+			url:    "ssh://10.0.0.1",
+		},
+		{
+			prompt: "[sudo] password for admin: ",
+			expect: Query{Kind: KindPassword, Scheme: "sudo", User: "admin", Host: hostname},
+			url:    "sudo://" + hostname,
+		},
+	}
+
+	for _, c := range cases {
+		q, ok := ParsePrompt(c.prompt)
+		if !ok {
+			t.Fatalf("Prompt %q not parsed", c.prompt)
+		}
+
+		if q != c.expect {
+			t.Fatalf("Prompt %q: got %+v, expected %+v", c.prompt, q, c.expect)
+		}
+
+		if q.URL() != c.url {
+			t.Fatalf("Prompt %q: got url %s, expected %s", c.prompt, q.URL(), c.url)
+		}
+	}
+
+	if _, ok := ParsePrompt("Enter passphrase for key '/home/user/.ssh/id_ed25519': "); ok {
+		t.Fatal("Key passphrase prompt must not be parsed")
+	}
+}
+
+func TestAsker_Fallback(t *testing.T) {
+	var (
+		gotPrompt string
+		gotEcho   bool
+	)
+
+	a := Asker{Fallback: func(prompt string, echo bool) (string, error) {
+		gotPrompt, gotEcho = prompt, echo
+		return "answer", nil
+	}}
+
+	answer, err := a.Ask(context.Background(), "Username for 'https://github.com': ")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if answer != "answer" || gotPrompt != "Username for 'https://github.com': " || !gotEcho {
+		t.Fatalf("Unexpected fallback call: answer %q, prompt %q, echo %t", answer, gotPrompt, gotEcho)
+	}
+
+	if _, err = (&Asker{}).Ask(context.Background(), "Unknown: "); err == nil {
+		t.Fatal("Expected error without fallback")
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/askpass"
+	"github.com/xakep666/gkpxc/envrun"
+	"github.com/xakep666/gkpxc/internal/bootstrap"
+)
+
+const service = "ssh-askpass-keepassxc"
+
+// debugEnv enables protocol tracing like --debug flag.
+const debugEnv = "SSH_ASKPASS_KEEPASSXC_DEBUG"
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix(service + ": ")
+
+	args, debug := bootstrap.ParseDebugFlag(os.Args[1:])
+	debug = debug || os.Getenv(debugEnv) != ""
+
+	value, err := run(context.Background(), strings.Join(args, " "), debug)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fmt.Println(value)
+}
+
+// run asks value for prompt. It's separated from main so deferred client close runs before exit.
+func run(ctx context.Context, prompt string, debug bool) (string, error) {
+	asker := askpass.Asker{Fallback: askpass.TerminalFallback}
+
+	if _, ok := askpass.ParsePrompt(prompt); ok {
+		client, err := connect(ctx, debug)
+		if err == nil {
+			defer client.Close()
+			asker.Resolver = &envrun.Resolver{Client: client}
+		} else {
+			log.Println("KeepassXC unavailable:", err)
+		}
+	}
+
+	return asker.Ask(ctx, prompt)
+}
+
+func connect(ctx context.Context, debug bool) (*gkpxc.Client, error) {
+	kr, err := bootstrap.SetupKeyring(service, os.Getenv("SSH_ASKPASS_KEEPASSXC_ASKPASS"))
+	if err != nil {
+		return nil, fmt.Errorf("keyring for private key open failed: %w", err)
+	}
+
+	var opts []gkpxc.ClientOption
+	if debug {
+		opts = bootstrap.DebugOptions(os.Stderr)
+	}
+
+	return bootstrap.Connect(ctx, kr, opts...)
+}
@@ -0,0 +1,120 @@
+// Package askpass allows to answer password prompts of ssh, sudo, git and similar tools using KeepassXC database.
+package askpass
+
+import (
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Kind is a kind of requested value.
+type Kind int
+
+const (
+	KindPassword Kind = iota
+	KindUsername
+	KindTOTP
+)
+
+// Query describes value requested by prompt.
+type Query struct {
+	Kind Kind
+
+	// Scheme is a scheme used to build lookup URL, i.e. "ssh", "sudo", "https".
+	Scheme string
+
+	// User is a user name, may be empty.
+	User string
+
+	// Host is a host name, may be empty if prompt doesn't contain it.
+	Host string
+
+	// Path is an URL path, used for http(s) URLs.
+	Path string
+}
+
+// URL returns lookup URL for KeepassXC. User name not included because KeepassXC matches URLs by host.
+func (q Query) URL() string {
+	return (&url.URL{Scheme: q.Scheme, Host: q.Host, Path: q.Path}).String()
+}
+
+var (
+	// ssh password authentication: "user@host's password: ".
+	sshPasswordRe = regexp.MustCompile(`^(?:(\S+)@)?(\S+?)'s password:\s*$`)
+
+	// ssh keyboard-interactive prefix: "(user@host) Password: ".
+	sshInteractiveRe = regexp.MustCompile(`^\((?:(\S+)@)?(\S+?)\)\s+(.*)$`)
+
+	// sudo: "[sudo] password for user: ".
+	sudoRe = regexp.MustCompile(`^\[sudo\] password for (\S+?):\s*$`)
+
+	// git and other tools: "Password for 'https://user@host': ", "Username for 'https://host': ".
+	quotedURLRe = regexp.MustCompile(`^(Password|Username) for '([^']+)':\s*$`)
+
+	// generic: "Password for user@host: ".
+	passwordForRe = regexp.MustCompile(`^Password for (?:(\S+)@)?(\S+?):\s*$`)
+
+	totpRe = regexp.MustCompile(`(?i)(verification code|one-time password|otp|authenticator code|token code)`)
+)
+
+// ParsePrompt extracts requested value from prompt text. False returned if prompt is not recognized.
+func ParsePrompt(prompt string) (Query, bool) {
+	prompt = strings.TrimSpace(prompt)
+
+	if m := sshPasswordRe.FindStringSubmatch(prompt); m != nil {
+		return Query{Kind: KindPassword, Scheme: "ssh", User: m[1], Host: m[2]}, true
+	}
+
+	if m := sshInteractiveRe.FindStringSubmatch(prompt); m != nil {
+		q, ok := ParseQuestion(m[3])
+		q.Scheme, q.User, q.Host = "ssh", m[1], m[2]
+		return q, ok
+	}
+
+	if m := sudoRe.FindStringSubmatch(prompt); m != nil {
+		hostname, _ := os.Hostname()
+		return Query{Kind: KindPassword, Scheme: "sudo", User: m[1], Host: hostname}, hostname != ""
+	}
+
+	if m := quotedURLRe.FindStringSubmatch(prompt); m != nil {
+		u, err := url.Parse(m[2])
+		if err != nil || u.Host == "" {
+			return Query{}, false
+		}
+
+		q := Query{Kind: KindPassword, Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+		if m[1] == "Username" {
+			q.Kind = KindUsername
+		}
+
+		if u.User != nil {
+			q.User = u.User.Username()
+		}
+
+		return q, true
+	}
+
+	if m := passwordForRe.FindStringSubmatch(prompt); m != nil {
+		return Query{Kind: KindPassword, Scheme: "ssh", User: m[1], Host: m[2]}, true
+	}
+
+	return Query{}, false
+}
+
+// ParseQuestion determines kind of value requested by prompt without host information,
+// i.e. keyboard-interactive question like "Password: " or "Verification code: ".
+func ParseQuestion(question string) (Query, bool) {
+	lower := strings.ToLower(question)
+
+	switch {
+	case totpRe.MatchString(question):
+		return Query{Kind: KindTOTP}, true
+	case strings.Contains(lower, "password") || strings.Contains(lower, "passcode"):
+		return Query{Kind: KindPassword}, true
+	case strings.Contains(lower, "username") || strings.Contains(lower, "login"):
+		return Query{Kind: KindUsername}, true
+	default:
+		return Query{}, false
+	}
+}
@@ -0,0 +1,51 @@
+package askpass
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// PasswordAuthMethod returns ssh password authentication method which takes password for user@host using Asker.
+func PasswordAuthMethod(ctx context.Context, a *Asker, user, host string) ssh.AuthMethod {
+	return ssh.PasswordCallback(func() (string, error) {
+		return a.AskQuery(ctx, fmt.Sprintf("%s@%s's password: ", user, host), Query{
+			Kind:   KindPassword,
+			Scheme: "ssh",
+			User:   user,
+			Host:   host,
+		})
+	})
+}
+
+// KeyboardInteractiveAuthMethod returns ssh keyboard-interactive authentication method
+// which answers password and verification code questions for user@host using Asker.
+func KeyboardInteractiveAuthMethod(ctx context.Context, a *Asker, user, host string) ssh.AuthMethod {
+	return ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		for i, question := range questions {
+			q, ok := ParseQuestion(question)
+			if !ok {
+				// let fallback answer unknown questions
+				q.Kind = KindUsername
+				if !echos[i] {
+					q.Kind = KindPassword
+				}
+
+				q.Host = ""
+			} else {
+				q.Scheme, q.User, q.Host = "ssh", user, host
+			}
+
+			answer, err := a.AskQuery(ctx, fmt.Sprintf("(%s@%s) %s", user, host, question), q)
+			if err != nil {
+				return nil, err
+			}
+
+			answers[i] = answer
+		}
+
+		return answers, nil
+	})
+}
@@ -0,0 +1,33 @@
+package askpass
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// TerminalFallback asks value from controlling terminal.
+func TerminalFallback(prompt string, echo bool) (string, error) {
+	tty, err := openTTY()
+	if err != nil {
+		return "", fmt.Errorf("open terminal: %w", err)
+	}
+
+	defer tty.Close()
+
+	if _, err = fmt.Fprint(tty, prompt); err != nil {
+		return "", err
+	}
+
+	if echo {
+		line, err := bufio.NewReader(tty).ReadString('\n')
+		return strings.TrimRight(line, "\r\n"), err
+	}
+
+	value, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+
+	return string(value), err
+}
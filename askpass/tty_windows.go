@@ -0,0 +1,31 @@
+package askpass
+
+import "os"
+
+// ttyFile combines console input and output handles.
+type ttyFile struct {
+	*os.File
+	out *os.File
+}
+
+func openTTY() (*ttyFile, error) {
+	in, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := os.OpenFile("CONOUT$", os.O_RDWR, 0)
+	if err != nil {
+		in.Close()
+		return nil, err
+	}
+
+	return &ttyFile{File: in, out: out}, nil
+}
+
+func (t *ttyFile) Write(p []byte) (int, error) { return t.out.Write(p) }
+
+func (t *ttyFile) Close() error {
+	t.out.Close()
+	return t.File.Close()
+}
@@ -0,0 +1,257 @@
+package gkpxc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// AssociationFormatVersion is a current version of AssociationCredentials serialization format.
+const AssociationFormatVersion = 1
+
+var (
+	// ErrUnsupportedAssociationFormat returned if serialized association credentials format is not recognized.
+	ErrUnsupportedAssociationFormat = errors.New("unsupported association credentials format")
+
+	// ErrInvalidKey returned if serialized key has invalid size.
+	ErrInvalidKey = fmt.Errorf("key must be %d bytes", KeySize)
+)
+
+// associationJSON is a versioned serialization format of AssociationCredentials.
+type associationJSON struct {
+	FormatVersion    int        `json:"formatVersion"`
+	ID               string     `json:"id"`
+	Hash             string     `json:"hash"`
+	KeepassXCVersion string     `json:"keepassxcVersion,omitempty"`
+	PublicKey        []byte     `json:"publicKey"`
+	PrivateKey       []byte     `json:"privateKey,omitempty"`
+	CreatedAt        *time.Time `json:"createdAt,omitempty"`
+}
+
+// legacyAssociationJSON is how AssociationCredentials were serialized by encoding/json before versioned format.
+type legacyAssociationJSON struct {
+	ID, Hash, Version     string
+	PrivateKey, PublicKey [KeySize]byte
+}
+
+// browserAssociationJSON is an association entry of KeePassXC-Browser settings export ("keyRing" object values).
+// Browser keeps only public part of identification key.
+type browserAssociationJSON struct {
+	ID      string          `json:"id"`
+	Hash    string          `json:"hash"`
+	Key     []byte          `json:"key"`
+	IDKey   []byte          `json:"idKey"`
+	Created json.RawMessage `json:"created"`
+}
+
+// MarshalJSON serializes credentials in versioned format with base64-encoded keys.
+func (a AssociationCredentials) MarshalJSON() ([]byte, error) {
+	v := associationJSON{
+		FormatVersion:    AssociationFormatVersion,
+		ID:               a.ID,
+		Hash:             a.Hash,
+		KeepassXCVersion: a.Version,
+		PublicKey:        a.PublicKey[:],
+	}
+
+	if a.PrivateKey != ([KeySize]byte{}) {
+		v.PrivateKey = a.PrivateKey[:]
+	}
+
+	if !a.CreatedAt.IsZero() {
+		v.CreatedAt = &a.CreatedAt
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON parses credentials serialized in versioned format, legacy format (encoding/json defaults)
+// or KeePassXC-Browser association export format. Credentials imported from browser have no private key.
+func (a *AssociationCredentials) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	// encoding/json matches fields case-insensitively so exact field names checked to detect format
+	has := func(name string) bool { _, ok := fields[name]; return ok }
+
+	switch {
+	case has("formatVersion"):
+		return a.unmarshalVersioned(data)
+	case has("PublicKey"), has("PrivateKey"):
+		return a.unmarshalLegacy(data)
+	case has("id") && (has("idKey") || has("key")):
+		return a.unmarshalBrowser(data)
+	default:
+		return ErrUnsupportedAssociationFormat
+	}
+}
+
+// MarshalText is the same as MarshalJSON.
+func (a AssociationCredentials) MarshalText() ([]byte, error) { return a.MarshalJSON() }
+
+// UnmarshalText is the same as UnmarshalJSON.
+func (a *AssociationCredentials) UnmarshalText(text []byte) error { return a.UnmarshalJSON(text) }
+
+func (a *AssociationCredentials) unmarshalVersioned(data []byte) error {
+	var v associationJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if v.FormatVersion != AssociationFormatVersion {
+		return fmt.Errorf("%w: version %d", ErrUnsupportedAssociationFormat, v.FormatVersion)
+	}
+
+	*a = AssociationCredentials{ID: v.ID, Hash: v.Hash, Version: v.KeepassXCVersion}
+
+	if err := copyKey(&a.PublicKey, v.PublicKey); err != nil {
+		return fmt.Errorf("public key: %w", err)
+	}
+
+	if len(v.PrivateKey) > 0 {
+		if err := copyKey(&a.PrivateKey, v.PrivateKey); err != nil {
+			return fmt.Errorf("private key: %w", err)
+		}
+	}
+
+	if v.CreatedAt != nil {
+		a.CreatedAt = *v.CreatedAt
+	}
+
+	return nil
+}
+
+func (a *AssociationCredentials) unmarshalLegacy(data []byte) error {
+	var v legacyAssociationJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*a = AssociationCredentials{
+		ID:         v.ID,
+		Hash:       v.Hash,
+		Version:    v.Version,
+		PrivateKey: v.PrivateKey,
+		PublicKey:  v.PublicKey,
+	}
+
+	return nil
+}
+
+func (a *AssociationCredentials) unmarshalBrowser(data []byte) error {
+	var v browserAssociationJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	key := v.IDKey
+	if len(key) == 0 {
+		key = v.Key
+	}
+
+	*a = AssociationCredentials{ID: v.ID, Hash: v.Hash, CreatedAt: parseBrowserTime(v.Created)}
+
+	if err := copyKey(&a.PublicKey, key); err != nil {
+		return fmt.Errorf("id key: %w", err)
+	}
+
+	return nil
+}
+
+// parseBrowserTime parses time stored by browser as milliseconds since epoch or as date string.
+func parseBrowserTime(raw json.RawMessage) time.Time {
+	if ms, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+		return time.UnixMilli(ms)
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return time.Time{}
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func copyKey(dst *[KeySize]byte, src []byte) error {
+	if len(src) != KeySize {
+		return fmt.Errorf("%w, got %d", ErrInvalidKey, len(src))
+	}
+
+	copy(dst[:], src)
+
+	return nil
+}
+
+// UnmarshalAssociations parses list of association credentials. Accepted inputs are:
+//   - JSON array of credentials;
+//   - single credentials object;
+//   - KeePassXC-Browser settings export or its "keyRing" object (database hash -> association).
+func UnmarshalAssociations(data []byte) ([]AssociationCredentials, error) {
+	data = bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(data, []byte("[")) {
+		var list []AssociationCredentials
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, err
+		}
+
+		return list, nil
+	}
+
+	var single AssociationCredentials
+
+	err := json.Unmarshal(data, &single)
+	switch {
+	case errors.Is(err, nil):
+		return []AssociationCredentials{single}, nil
+	case errors.Is(err, ErrUnsupportedAssociationFormat):
+	default:
+		return nil, err
+	}
+
+	var settings struct {
+		KeyRing map[string]AssociationCredentials `json:"keyRing"`
+	}
+
+	if err = json.Unmarshal(data, &settings); err != nil {
+		return nil, err
+	}
+
+	keyRing := settings.KeyRing
+	if keyRing == nil {
+		if err = json.Unmarshal(data, &keyRing); err != nil {
+			return nil, err
+		}
+	}
+
+	hashes := make([]string, 0, len(keyRing))
+	for hash := range keyRing {
+		hashes = append(hashes, hash)
+	}
+
+	sort.Strings(hashes)
+
+	list := make([]AssociationCredentials, 0, len(keyRing))
+	for _, hash := range hashes {
+		cred := keyRing[hash]
+		if cred.Hash == "" {
+			cred.Hash = hash
+		}
+
+		list = append(list, cred)
+	}
+
+	return list, nil
+}
@@ -0,0 +1,127 @@
+package gkpxc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testAssociation() AssociationCredentials {
+	cred := AssociationCredentials{
+		ID:        "test",
+		Hash:      "hash",
+		Version:   "2.7.4",
+		CreatedAt: time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	for i := range cred.PublicKey {
+		cred.PublicKey[i] = byte(i)
+		cred.PrivateKey[i] = byte(KeySize + i)
+	}
+
+	return cred
+}
+
+func TestAssociationCredentials_Text(t *testing.T) {
+	cred := testAssociation()
+
+	text, err := cred.MarshalText()
+	if err != nil {
+		t.Fatal("MarshalText", err)
+	}
+
+	if !strings.Contains(string(text), `"formatVersion":1`) ||
+		!strings.Contains(string(text), base64.StdEncoding.EncodeToString(cred.PublicKey[:])) {
+		t.Fatalf("Unexpected serialized credentials %s", text)
+	}
+
+	var parsed AssociationCredentials
+	if err = parsed.UnmarshalText(text); err != nil {
+		t.Fatal("UnmarshalText", err)
+	}
+
+	if !reflect.DeepEqual(parsed, cred) {
+		t.Fatalf("Got %+v, expected %+v", parsed, cred)
+	}
+
+	if err = parsed.UnmarshalText([]byte(`{"formatVersion":2,"id":"test"}`)); !errors.Is(err, ErrUnsupportedAssociationFormat) {
+		t.Fatalf("Unexpected error %v, expected ErrUnsupportedAssociationFormat", err)
+	}
+}
+
+func TestAssociationCredentials_Legacy(t *testing.T) {
+	cred := testAssociation()
+	cred.CreatedAt = time.Time{}
+
+	// as it was serialized before versioned format
+	legacy, err := json.Marshal(legacyAssociationJSON{
+		ID:         cred.ID,
+		Hash:       cred.Hash,
+		Version:    cred.Version,
+		PrivateKey: cred.PrivateKey,
+		PublicKey:  cred.PublicKey,
+	})
+	if err != nil {
+		t.Fatal("Marshal", err)
+	}
+
+	var parsed AssociationCredentials
+	if err = json.Unmarshal(legacy, &parsed); err != nil {
+		t.Fatal("Unmarshal", err)
+	}
+
+	if !reflect.DeepEqual(parsed, cred) {
+		t.Fatalf("Got %+v, expected %+v", parsed, cred)
+	}
+}
+
+func TestUnmarshalAssociations(t *testing.T) {
+	pub := testAssociation().PublicKey
+	key := base64.StdEncoding.EncodeToString(pub[:])
+
+	browserExport := `{
+		"settings": {"autoFillAndSend": true},
+		"keyRing": {
+			"hash2": {"id": "browser2", "hash": "hash2", "key": "` + key + `", "created": "2022-06-01T12:00:00.000Z"},
+			"hash1": {"id": "browser1", "key": "` + key + `", "created": 1654084800000}
+		}
+	}`
+
+	list, err := UnmarshalAssociations([]byte(browserExport))
+	if err != nil {
+		t.Fatal("UnmarshalAssociations", err)
+	}
+
+	expect := []AssociationCredentials{
+		{ID: "browser1", Hash: "hash1", PublicKey: pub, CreatedAt: time.UnixMilli(1654084800000)},
+		{ID: "browser2", Hash: "hash2", PublicKey: pub, CreatedAt: testAssociation().CreatedAt},
+	}
+
+	if !reflect.DeepEqual(list, expect) {
+		t.Fatalf("Got %+v, expected %+v", list, expect)
+	}
+
+	single, err := json.Marshal(testAssociation())
+	if err != nil {
+		t.Fatal("Marshal", err)
+	}
+
+	for _, input := range []string{string(single), "[" + string(single) + "]"} {
+		list, err = UnmarshalAssociations([]byte(input))
+		if err != nil {
+			t.Fatal("UnmarshalAssociations", err)
+		}
+
+		if !reflect.DeepEqual(list, []AssociationCredentials{testAssociation()}) {
+			t.Fatalf("Got %+v for %s", list, input)
+		}
+	}
+
+	if _, err = UnmarshalAssociations([]byte(`{"id": "test"}`)); err == nil {
+		t.Fatalf("Error expected for unknown format")
+	}
+}
@@ -0,0 +1,56 @@
+package gkpxc
+
+import (
+	"context"
+	"encoding/json"
+)
+
+type callConfig struct {
+	triggerUnlock bool
+	testAssociate bool
+}
+
+// CallOption configures Client.Call and Client.CallRaw.
+type CallOption func(o *callConfig)
+
+// WithTriggerUnlock requests database unlock if it's locked.
+func WithTriggerUnlock() CallOption {
+	return func(o *callConfig) {
+		o.triggerUnlock = true
+	}
+}
+
+// WithTestAssociate tests association before call like most of built-in methods do.
+func WithTestAssociate() CallOption {
+	return func(o *callConfig) {
+		o.testAssociate = true
+	}
+}
+
+// Call performs action which has no dedicated method, i.e. added in newer KeepassXC.
+// Request is marshaled to JSON object ("action" field is added), nil means empty object.
+// Response is unmarshaled from decrypted message, it may be nil if not needed.
+// Messages are encrypted and validated same way as for built-in methods, error response returned as *ErrorResponse.
+func (c *Client) Call(ctx context.Context, action string, req, resp interface{}, opts ...CallOption) error {
+	var cfg callConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	if cfg.testAssociate {
+		if err := c.TestAssociate(ctx); err != nil {
+			return err
+		}
+	}
+
+	return c.call(ctx, action, cfg.triggerUnlock, req, resp)
+}
+
+// CallRaw is like Call but returns decrypted response as is. If decrypted response contains error it is returned too.
+func (c *Client) CallRaw(ctx context.Context, action string, req interface{}, opts ...CallOption) (json.RawMessage, error) {
+	var resp json.RawMessage
+
+	err := c.Call(ctx, action, req, &resp, opts...)
+
+	return resp, err
+}
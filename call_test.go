@@ -0,0 +1,64 @@
+package gkpxc_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/gkpxctest"
+)
+
+func TestClient_Call(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	uuid := srv.AddEntry(gkpxctest.Entry{URL: "https://example.com", TOTP: "123456"})
+
+	ctx := context.Background()
+	client := gkpxctest.NewAssociatedClient(t, srv)
+
+	var hash struct {
+		Hash string `json:"hash"`
+	}
+
+	if err := client.Call(ctx, "get-databasehash", nil, &hash); err != nil {
+		t.Fatal("Call", err)
+	}
+
+	if hash.Hash != srv.DatabaseHash() {
+		t.Fatalf("Unexpected hash %s, expected %s", hash.Hash, srv.DatabaseHash())
+	}
+
+	raw, err := client.CallRaw(ctx, "get-totp", map[string]string{"uuid": uuid}, gkpxc.WithTestAssociate())
+	if err != nil {
+		t.Fatal("CallRaw", err)
+	}
+
+	if !strings.Contains(string(raw), `"totp":"123456"`) || !strings.Contains(string(raw), `"action":"get-totp"`) {
+		t.Fatalf("Unexpected response %s", raw)
+	}
+
+	if _, err = client.CallRaw(ctx, "get-something-new", nil); !gkpxc.IsErrorCode(err, gkpxc.ErrCodeIncorrectAction) {
+		t.Fatalf("Unexpected error %v, expected ErrCodeIncorrectAction", err)
+	}
+}
+
+func TestClient_Call_notAssociated(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	client, err := gkpxc.NewClient(ctx, gkpxc.WithConn(srv.Dial()))
+	if err != nil {
+		t.Fatal("NewClient", err)
+	}
+
+	defer client.Close()
+
+	if err = client.Call(ctx, "get-totp", nil, nil, gkpxc.WithTestAssociate()); !errors.Is(err, gkpxc.ErrNotAssociated) {
+		t.Fatalf("Unexpected error %v, expected ErrNotAssociated", err)
+	}
+}
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
+	"sync"
+	"time"
 
 	"golang.org/x/crypto/nacl/box"
 )
@@ -29,15 +33,49 @@ var (
 	// ErrClosing may be sent if operation interrupted by closing.
 	ErrClosing = fmt.Errorf("closing")
 
+	// ErrConnectFailed returned if connection to KeepassXC socket/pipe failed, i.e. KeepassXC is not running
+	// or browser integration is disabled.
+	ErrConnectFailed = fmt.Errorf("connect failed")
+
+	// ErrInsecureSocket returned if KeepassXC socket or process listening it is owned by another user.
+	// It may mean that socket is spoofed to steal secrets.
+	ErrInsecureSocket = fmt.Errorf("insecure socket")
+
+	// ErrDatabaseMismatch returned if database hash reported by KeepassXC differs from one stored in association credentials.
+	ErrDatabaseMismatch = fmt.Errorf("database hash mismatch")
+
+	// ErrNoPasswordGenerated returned if KeepassXC response to password generation request has no password.
+	ErrNoPasswordGenerated = fmt.Errorf("no password generated")
+
 	// ErrNotAssociated returned if method requires association with database but no credentials present.
 	// In this case Client.Associate or Client.SetAssociationCredentials must be used.
 	ErrNotAssociated = fmt.Errorf("not associated")
 )
 
+// wrappedError marks error with sentinel keeping both of them matchable with errors.Is and errors.As.
+type wrappedError struct {
+	sentinel error
+	err      error
+}
+
+func wrapError(sentinel, err error) error {
+	return &wrappedError{sentinel: sentinel, err: err}
+}
+
+func (e *wrappedError) Error() string { return e.sentinel.Error() + ": " + e.err.Error() }
+
+func (e *wrappedError) Is(target error) bool { return target == e.sentinel }
+
+func (e *wrappedError) Unwrap() error { return e.err }
+
 // AssociationCredentials holds KeepassXC association credentials.
+// They're serialized in versioned format, see MarshalJSON.
 type AssociationCredentials struct {
 	ID, Hash, Version     string // returned from KeepassXC
 	PrivateKey, PublicKey [KeySize]byte
+
+	// CreatedAt is a time of association.
+	CreatedAt time.Time
 }
 
 type msgErrPair struct {
@@ -51,16 +89,29 @@ type Client struct {
 	closeConn bool
 
 	clientID              *[NonceSize]byte
-	privateKey, publicKey *[KeySize]byte
+	keysMu                sync.Mutex     // protects keys from wiping by Close
+	privateKey, publicKey *[KeySize]byte // private key wiped after handshake
 	sharedKey             *[KeySize]byte // computed after handshake
 	associationCred       *AssociationCredentials
-
-	// to support asynchronous signals from KeepassXC
-	stop               chan struct{}   // broadcast for readers and writers of channels below
-	requests           chan Message    // main->read
-	responses          chan msgErrPair // write->main
+	memoryLock            bool
+	secretBytes           bool
+	tracer                func(TraceEvent)
+	traceSecrets          bool
+	observer              Observer
+	interceptors          []Interceptor
+	capabilities          Capabilities
+	maxMessageSize        int
+
+	// to support asynchronous signals and late responses from KeepassXC
+	stop               chan struct{}        // broadcast for readers and writers of channels below
+	requests           chan outgoingMessage // main->write
+	pendingMu          sync.Mutex           // protects fields below
+	pending            []*pendingRequest    // waiting for response, read->main
+	abandoned          []*pendingRequest    // not waited anymore, responses for them dropped
+	readErr            error                // permanent read error
 	errorHandlers      []func(err error)
 	lockChangeHandlers []func(locked bool)
+	messageHandlers    []func(msg Message)
 }
 
 // NewClient creates KeepassXC client. By default, it connects to internal socket/pipe and associates as new client.
@@ -73,7 +124,7 @@ type Client struct {
 // 5. Make requests.
 // 6. Close client.
 func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
-	cfg := clientConfig{}
+	cfg := clientConfig{maxMessageSize: DefaultMaxMessageSize}
 	for _, o := range opts {
 		o(&cfg)
 	}
@@ -85,7 +136,7 @@ func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
 		var err error
 		conn, err = connect(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("connect: %w", err)
+			return nil, wrapError(ErrConnectFailed, err)
 		}
 
 		closeConn = true
@@ -105,15 +156,29 @@ func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
 		conn:      conn,
 		closeConn: closeConn,
 
-		clientID:   clientID,
-		privateKey: priv,
-		publicKey:  pub,
+		clientID:       clientID,
+		privateKey:     priv,
+		publicKey:      pub,
+		memoryLock:     cfg.memoryLock,
+		secretBytes:    cfg.secretBytes,
+		tracer:         cfg.tracer,
+		traceSecrets:   cfg.traceSecrets,
+		observer:       cfg.observer,
+		interceptors:   cfg.interceptors,
+		maxMessageSize: cfg.maxMessageSize,
 
 		stop:               make(chan struct{}),
-		requests:           make(chan Message),
-		responses:          make(chan msgErrPair),
+		requests:           make(chan outgoingMessage),
 		errorHandlers:      cfg.errorHandlers,
 		lockChangeHandlers: cfg.lockChangeHandlers,
+		messageHandlers:    cfg.messageHandlers,
+	}
+
+	if cfg.memoryLock {
+		if err = lockMemory(priv[:]); err != nil {
+			defer client.Close()
+			return nil, fmt.Errorf("lock memory: %w", err)
+		}
 	}
 
 	go client.write()
@@ -128,7 +193,11 @@ func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
 }
 
 func (c *Client) handshake(ctx context.Context) error {
-	if c.sharedKey != nil {
+	c.keysMu.Lock()
+	done := c.sharedKey != nil
+	c.keysMu.Unlock()
+
+	if done {
 		return nil // handshake already done
 	}
 
@@ -137,18 +206,50 @@ func (c *Client) handshake(ctx context.Context) error {
 		return fmt.Errorf("generate nonce: %w", err)
 	}
 
-	resp, err := c.exchange(ctx, Message{
+	req := Message{
 		Action:    "change-public-keys",
 		Nonce:     (*nonce)[:],
 		ClientID:  (*c.clientID)[:],
 		PublicKey: (*c.publicKey)[:],
+	}
+
+	var resp Message
+
+	err = c.intercept(ctx, req.Action, req, &resp, func(ctx context.Context, _ string, req, resp interface{}) error {
+		reqMsg, reqOK := req.(Message)
+		respMsg, respOK := resp.(*Message)
+		if !reqOK || !respOK {
+			return invalidInvokerArgs(req, resp)
+		}
+
+		var err error
+		*respMsg, err = c.instrumentedExchange(ctx, reqMsg)
+
+		return err
 	})
 	if err != nil {
 		return err
 	}
 
-	c.sharedKey = new([KeySize]byte)
-	box.Precompute(c.sharedKey, (*[KeySize]byte)(resp.PublicKey), c.privateKey)
+	// unknown version is not fatal, everything considered supported then
+	version, _ := ParseVersion(resp.Version)
+	c.capabilities = capabilitiesOf(version)
+
+	c.keysMu.Lock()
+	defer c.keysMu.Unlock()
+
+	sharedKey := new([KeySize]byte)
+	if c.memoryLock {
+		if err = lockMemory(sharedKey[:]); err != nil {
+			return fmt.Errorf("lock memory: %w", err)
+		}
+	}
+
+	box.Precompute(sharedKey, (*[KeySize]byte)(resp.PublicKey), c.privateKey)
+	c.sharedKey = sharedKey
+
+	// private key is not needed anymore
+	wipeKey(c.privateKey)
 
 	return nil
 }
@@ -187,27 +288,61 @@ func (c *Client) Associate(ctx context.Context) error {
 		Version:    resp.Version,
 		PublicKey:  *pubID,
 		PrivateKey: *privID,
+		CreatedAt:  time.Now(),
 	}
 
 	return nil
 }
 
+// Capabilities returns features supported by connected KeepassXC.
+func (c *Client) Capabilities() Capabilities { return c.capabilities }
+
 // AssociationCredentials returns stored associations credentials. They're valid only for one database.
 func (c *Client) AssociationCredentials() *AssociationCredentials { return c.associationCred }
 
 // SetAssociationCredentials can be used to set association existing association credentials.
 func (c *Client) SetAssociationCredentials(cred *AssociationCredentials) { c.associationCred = cred }
 
+// RotateAssociation requests a new association with fresh identification key and replaces current credentials.
+// Old credentials are returned so caller can retire them in its storage. Browser protocol has no action to remove
+// association so old one is kept in database settings until user removes it (or overwrites it by giving same name to new one).
+// On failure current credentials are kept.
+func (c *Client) RotateAssociation(ctx context.Context) (*AssociationCredentials, error) {
+	old := c.associationCred
+	if old == nil {
+		return nil, ErrNotAssociated
+	}
+
+	if err := c.Associate(ctx); err != nil {
+		return nil, err
+	}
+
+	return old, nil
+}
+
 // TestAssociate tests association with database. Association credentials must present.
+// If credentials contain database hash, it must match hash reported by KeepassXC, otherwise ErrDatabaseMismatch returned.
+// So secrets are not sent to database other than associated one.
 func (c *Client) TestAssociate(ctx context.Context) error {
 	if c.associationCred == nil {
 		return ErrNotAssociated
 	}
 
-	return c.exchangeEncrypted(ctx, false, TestAssociateRequest{
+	var resp TestAssociateResponse
+
+	err := c.exchangeEncrypted(ctx, false, TestAssociateRequest{
 		ID:  c.associationCred.ID,
 		Key: c.associationCred.PublicKey[:],
-	}, &TestAssociateResponse{})
+	}, &resp)
+	if err != nil {
+		return err
+	}
+
+	if c.associationCred.Hash != "" && resp.Hash != c.associationCred.Hash {
+		return fmt.Errorf("%w: associated with %s, got %s", ErrDatabaseMismatch, c.associationCred.Hash, resp.Hash)
+	}
+
+	return nil
 }
 
 // GetDatabaseGroups returns database groups. Association credentials must present.
@@ -240,6 +375,7 @@ func (c *Client) CreateNewGroup(ctx context.Context, req CreateNewGroupRequest)
 }
 
 // GetLogins queries for database entries by URL.
+// If client created with WithSecretBytes passwords returned in LoginEntry.PasswordBytes.
 func (c *Client) GetLogins(ctx context.Context, req GetLoginsRequest) (GetLoginsResponse, error) {
 	if err := c.TestAssociate(ctx); err != nil {
 		return GetLoginsResponse{}, err
@@ -251,6 +387,10 @@ func (c *Client) GetLogins(ctx context.Context, req GetLoginsRequest) (GetLogins
 		Key: c.associationCred.PublicKey[:],
 	}}, req.Keys...)
 
+	if c.secretBytes {
+		return c.getLoginsSecretBytes(ctx, req)
+	}
+
 	var resp GetLoginsResponse
 	if err := c.exchangeEncrypted(ctx, false, req, &resp); err != nil {
 		return GetLoginsResponse{}, err
@@ -259,6 +399,33 @@ func (c *Client) GetLogins(ctx context.Context, req GetLoginsRequest) (GetLogins
 	return resp, nil
 }
 
+func (c *Client) getLoginsSecretBytes(ctx context.Context, req GetLoginsRequest) (GetLoginsResponse, error) {
+	var resp secretGetLoginsResponse
+
+	err := c.exchangeEncrypted(ctx, false, req, &resp)
+
+	ret := GetLoginsResponse{
+		ErrorFields: resp.ErrorFields,
+		Count:       resp.Count,
+		Entries:     make([]LoginEntry, 0, len(resp.Entries)),
+	}
+
+	for _, entry := range resp.Entries {
+		entry.LoginEntry.PasswordBytes = entry.Password
+		ret.Entries = append(ret.Entries, entry.LoginEntry)
+	}
+
+	if err != nil {
+		for _, entry := range ret.Entries {
+			wipe(entry.PasswordBytes)
+		}
+
+		return GetLoginsResponse{}, err
+	}
+
+	return ret, nil
+}
+
 // SetLogin creates or updates existing login.
 func (c *Client) SetLogin(ctx context.Context, req SetLoginRequest) error {
 	if err := c.TestAssociate(ctx); err != nil {
@@ -268,22 +435,49 @@ func (c *Client) SetLogin(ctx context.Context, req SetLoginRequest) error {
 	return c.exchangeEncrypted(ctx, false, req, &SetLoginResponse{})
 }
 
-// DeleteEntry deletes entry.
+// DeleteEntry deletes entry. Requires KeepassXC 2.7.0 or newer, otherwise ErrUnsupportedByServer returned.
 func (c *Client) DeleteEntry(ctx context.Context, req DeleteEntryRequest) error {
+	if err := c.checkSupported(req.Action(), c.capabilities.DeleteEntry); err != nil {
+		return err
+	}
+
 	if err := c.TestAssociate(ctx); err != nil {
 		return err
 	}
 
-	return c.exchangeEncrypted(ctx, false, req, &DeleteEntryResponse{})
+	err := c.exchangeEncrypted(ctx, false, req, &DeleteEntryResponse{})
+	if IsErrorCode(err, ErrCodeIncorrectAction) {
+		return wrapError(ErrUnsupportedByServer, err)
+	}
+
+	return err
 }
 
-// GeneratePassword requests to show generate password dialog.
-func (c *Client) GeneratePassword(ctx context.Context) error {
+// GeneratePassword shows password generator dialog and waits until user accepts generated password.
+// Use context with deadline or cancel it to stop waiting, late response is dropped then.
+// If dialog closed without accepting password KeepassXC responds with ErrCodeActionCancelledOrDenied.
+// Note that KeepassXC denies new request while dialog from previous one is shown.
+func (c *Client) GeneratePassword(ctx context.Context) (string, error) {
 	if err := c.TestAssociate(ctx); err != nil {
-		return err
+		return "", err
+	}
+
+	requestID, err := generateRequestID()
+	if err != nil {
+		return "", fmt.Errorf("generate request id: %w", err)
+	}
+
+	var resp GeneratePasswordResponse
+	if err = c.exchangeEncrypted(ctx, false, GeneratePasswordRequest{RequestID: requestID}, &resp); err != nil {
+		return "", err
+	}
+
+	password := resp.GeneratedPassword()
+	if password == "" {
+		return "", ErrNoPasswordGenerated
 	}
 
-	return c.exchangeEncrypted(ctx, false, GeneratePasswordRequest{}, &GeneratePasswordResponse{})
+	return password, nil
 }
 
 // LockDatabase locks current database.
@@ -319,69 +513,105 @@ func (c *Client) RequestAutoType(ctx context.Context, req AutoTypeRequest) error
 }
 
 func (c *Client) write() {
+	encoder := json.NewEncoder(c.conn)
+
 	for {
 		select {
 		case <-c.stop:
 			return
 		case req := <-c.requests:
-			err := json.NewEncoder(c.conn).Encode(req)
-			if err == nil {
-				break
-			}
-
-			// transfer error to caller
-			select {
-			case <-c.stop:
-				return
-			case c.responses <- msgErrPair{error: err}:
+			if err := encoder.Encode(req.Message); err != nil {
+				// transfer error to caller
+				c.complete(req.pending, msgErrPair{error: wrapError(ErrConnectionLost, err)})
 			}
 		}
 	}
 }
 
 func (c *Client) read() {
-	decoder := json.NewDecoder(c.conn)
+	frames := newFrameReader(c.conn, c.maxMessageSize)
 	for {
-		var (
-			msg Message
-			err error
-		)
+		frame, err := frames.next()
+		switch {
+		case errors.Is(err, ErrInvalidMessage):
+			c.handleError(err)
+			continue
+		case err != nil:
+			// stream can't be read further
+			select {
+			case <-c.stop:
+			default:
+				c.handleError(err)
+			}
 
-		if err = decoder.Decode(&msg); err == nil {
-			err = msg.asError()
+			c.failPending(wrapError(ErrConnectionLost, err))
+
+			return
 		}
 
+		var msg Message
+
+		if err = json.Unmarshal(frame, &msg); err != nil {
+			// message can't be attributed to request, caller waits for response until context is done
+			c.handleError(fmt.Errorf("%w: %s", ErrInvalidMessage, err))
+
+			continue
+		}
+
+		err = msg.asError()
+
 		switch msg.Action {
 		case "database-locked", "database-unlocked":
 			locked := msg.Action == "database-locked"
+			if c.observer != nil {
+				c.observer.LockStateChanged(locked)
+			}
+
 			for _, h := range c.lockChangeHandlers {
 				go h(locked)
 			}
 
+			c.handleUnsolicited(msg)
+
 			continue
 		case "":
 			if err != nil {
-				for _, h := range c.errorHandlers {
-					go h(err)
-				}
+				c.handleError(err)
 			}
 		}
 
-		select {
-		case <-c.stop:
-			return
-		case c.responses <- msgErrPair{Message: msg, error: err}:
+		if !c.route(msgErrPair{Message: msg, error: err}) {
+			c.handleUnsolicited(msg)
 		}
 	}
 }
 
+func (c *Client) handleError(err error) {
+	for _, h := range c.errorHandlers {
+		go h(err)
+	}
+}
+
+func (c *Client) handleUnsolicited(msg Message) {
+	for _, h := range c.messageHandlers {
+		go h(msg)
+	}
+}
+
 func (c *Client) exchange(ctx context.Context, req Message) (Message, error) {
+	p, err := c.addPending(req)
+	if err != nil {
+		return Message{}, err
+	}
+
 	select {
 	case <-c.stop:
+		c.abandon(p)
 		return Message{}, ErrClosing
-	case c.requests <- req:
+	case c.requests <- outgoingMessage{Message: req, pending: p}:
 		// pass
 	case <-ctx.Done():
+		c.abandon(p)
 		return Message{}, ctx.Err()
 	}
 
@@ -389,9 +619,12 @@ func (c *Client) exchange(ctx context.Context, req Message) (Message, error) {
 
 	select {
 	case <-c.stop:
+		c.abandon(p)
 		return Message{}, ErrClosing
-	case resp = <-c.responses:
+	case resp = <-p.response:
 	case <-ctx.Done():
+		// response may come later, it will be dropped
+		c.abandon(p)
 		return Message{}, ctx.Err()
 	}
 
@@ -406,6 +639,51 @@ func (c *Client) exchange(ctx context.Context, req Message) (Message, error) {
 	return resp.Message, nil
 }
 
+// instrumentedExchange is exchange of unencrypted messages with tracing and observing.
+func (c *Client) instrumentedExchange(ctx context.Context, req Message) (Message, error) {
+	if c.tracer == nil && c.observer == nil {
+		return c.exchange(ctx, req)
+	}
+
+	start := time.Now()
+
+	var done func(ActionStats)
+	if c.observer != nil {
+		done = c.observer.StartAction(ctx, req.Action)
+	}
+
+	reqJSON, _ := json.Marshal(req)
+
+	resp, err := c.exchange(ctx, req)
+
+	var respJSON []byte
+	if err == nil {
+		respJSON, _ = json.Marshal(resp)
+	}
+
+	if c.tracer != nil {
+		event := TraceEvent{Action: req.Action, Start: start, NonceValid: err == nil}
+		if reqJSON != nil {
+			event.Request = c.traceJSON(reqJSON)
+		}
+
+		if respJSON != nil {
+			event.Response = c.traceJSON(respJSON)
+		}
+
+		event.finish(err)
+		c.tracer(event)
+	}
+
+	if done != nil {
+		stats := ActionStats{Action: req.Action, Start: start, RequestBytes: len(reqJSON), ResponseBytes: len(respJSON)}
+		stats.finish(err)
+		done(stats)
+	}
+
+	return resp, err
+}
+
 type plainReq interface {
 	Action() string
 }
@@ -415,58 +693,168 @@ type plainResp interface {
 }
 
 func (c *Client) exchangeEncrypted(ctx context.Context, triggerUnlock bool, req plainReq, resp plainResp) error {
+	return c.call(ctx, req.Action(), triggerUnlock, req, resp)
+}
+
+// call performs encrypted exchange through interceptors chain.
+func (c *Client) call(ctx context.Context, action string, triggerUnlock bool, req, resp interface{}) error {
+	if len(c.interceptors) == 0 {
+		return c.invokeEncrypted(ctx, action, triggerUnlock, req, resp)
+	}
+
+	return c.intercept(ctx, action, req, resp, func(ctx context.Context, action string, req, resp interface{}) error {
+		return c.invokeEncrypted(ctx, action, triggerUnlock, req, resp)
+	})
+}
+
+// invokeEncrypted sends encrypted request and decrypts response. Response may be nil if it's not needed.
+// Errors are taken from response if it implements plainResp, otherwise from common error fields.
+func (c *Client) invokeEncrypted(ctx context.Context, action string, triggerUnlock bool, req, resp interface{}) (err error) {
+	var event *TraceEvent
+	if c.tracer != nil {
+		event = &TraceEvent{Action: action, Start: time.Now()}
+		defer func() {
+			event.finish(err)
+			c.tracer(*event)
+		}()
+	}
+
+	var stats *ActionStats
+	if c.observer != nil {
+		done := c.observer.StartAction(ctx, action)
+		stats = &ActionStats{Action: action, Start: time.Now()}
+		defer func() {
+			stats.finish(err)
+			done(*stats)
+		}()
+	}
+
 	nonce, err := generateNonce()
 	if err != nil {
 		return fmt.Errorf("generate nonce: %w", err)
 	}
 
-	// stdlib-only way to add "action" field
+	buf := getBuffer()
+	defer putBuffer(buf) // also wipes request and response
 
-	var reqMap map[string]interface{}
-
-	msg, err := json.Marshal(req)
-	if err != nil {
+	if err = encodeRequest(buf, action, req); err != nil {
 		return fmt.Errorf("marshal request: %w", err)
 	}
 
-	if err = json.Unmarshal(msg, &reqMap); err != nil {
-		return fmt.Errorf("unmarshal request: %w", err)
+	msg := buf.Bytes()
+
+	if event != nil {
+		event.Request = c.traceJSON(msg)
 	}
 
-	reqMap["action"] = req.Action()
+	if stats != nil {
+		stats.RequestBytes = len(msg)
+	}
 
-	msg, err = json.Marshal(reqMap)
+	sealed, err := c.seal(msg, nonce)
 	if err != nil {
-		return fmt.Errorf("marshal request: %w", err)
+		return err
 	}
 
-	res, err := c.exchange(ctx, Message{
-		Action:        req.Action(),
-		Message:       box.SealAfterPrecomputation(nil, msg, nonce, c.sharedKey),
+	outgoing := Message{
+		Action:        action,
+		Message:       sealed,
 		Nonce:         (*nonce)[:],
 		ClientID:      (*c.clientID)[:],
 		TriggerUnlock: triggerUnlock,
-	})
+	}
+
+	if withID, ok := req.(interface{ requestID() string }); ok {
+		outgoing.RequestID = withID.requestID()
+	}
+
+	res, err := c.exchange(ctx, outgoing)
 	if err != nil {
 		return err
 	}
 
-	decrypted, ok := box.OpenAfterPrecomputation(nil, res.Message, (*[NonceSize]byte)(res.Nonce), c.sharedKey)
-	if !ok {
-		return ErrDecryptFailed
+	if event != nil {
+		event.NonceValid = true
+	}
+
+	// request is sealed already so buffer can be reused, it's wiped anyway
+	decrypted, err := c.open(buf.Bytes()[:0], res.Message, res.Nonce)
+	if err != nil {
+		return err
+	}
+
+	defer wipe(decrypted) // in case buffer was too small
+
+	if event != nil {
+		event.Response = c.traceJSON(decrypted)
+	}
+
+	if stats != nil {
+		stats.ResponseBytes = len(decrypted)
+	}
+
+	if resp != nil {
+		if err = json.Unmarshal(decrypted, resp); err != nil {
+			return fmt.Errorf("unmarshal response: %w", err)
+		}
+	}
+
+	if typedResp, ok := resp.(plainResp); ok {
+		return typedResp.asError()
 	}
 
-	if err = json.Unmarshal(decrypted, resp); err != nil {
+	var fields ErrorFields
+	if err = json.Unmarshal(decrypted, &fields); err != nil {
 		return fmt.Errorf("unmarshal response: %w", err)
 	}
 
-	return resp.asError()
+	return fields.asError()
+}
+
+func (c *Client) seal(msg []byte, nonce *[NonceSize]byte) ([]byte, error) {
+	c.keysMu.Lock()
+	defer c.keysMu.Unlock()
+
+	if c.sharedKey == nil {
+		return nil, ErrClosing
+	}
+
+	return box.SealAfterPrecomputation(nil, msg, nonce, c.sharedKey), nil
 }
 
-// Close stops sending and receiving messages.
+// open decrypts message appending it to out.
+func (c *Client) open(out, msg, nonce []byte) ([]byte, error) {
+	c.keysMu.Lock()
+	defer c.keysMu.Unlock()
+
+	if c.sharedKey == nil {
+		return nil, ErrClosing
+	}
+
+	decrypted, ok := box.OpenAfterPrecomputation(out, msg, (*[NonceSize]byte)(nonce), c.sharedKey)
+	if !ok {
+		return nil, ErrDecryptFailed
+	}
+
+	return decrypted, nil
+}
+
+// Close stops sending and receiving messages. Key material is wiped.
 func (c *Client) Close() error {
 	close(c.stop)
 
+	c.keysMu.Lock()
+	for _, key := range []*[KeySize]byte{c.privateKey, c.sharedKey} {
+		wipeKey(key)
+
+		if key != nil && c.memoryLock {
+			_ = unlockMemory(key[:])
+		}
+	}
+
+	c.sharedKey = nil
+	c.keysMu.Unlock()
+
 	if c.closeConn {
 		return c.conn.Close()
 	}
@@ -480,6 +868,15 @@ func generateNonce() (*[NonceSize]byte, error) {
 	return &ret, err
 }
 
+func generateRequestID() (string, error) {
+	var ret [8]byte
+	if _, err := rand.Read(ret[:]); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(ret[:]), nil
+}
+
 func incrementNonce(nonce []byte) []byte {
 	ret := append([]byte{}, nonce...)
 
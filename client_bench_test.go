@@ -0,0 +1,66 @@
+package gkpxc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/gkpxctest"
+)
+
+func BenchmarkClient_Handshake(b *testing.B) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		client, err := gkpxc.NewClient(context.Background(), gkpxc.WithConn(srv.Dial()))
+		if err != nil {
+			b.Fatal("NewClient", err)
+		}
+
+		client.Close()
+	}
+}
+
+func BenchmarkClient_GetLogins(b *testing.B) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	for i := 0; i < 10; i++ {
+		srv.AddEntry(gkpxctest.Entry{URL: "https://example.com", Login: "user", Password: "password"})
+	}
+
+	client := gkpxctest.NewAssociatedClient(b, srv)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := client.GetLogins(ctx, gkpxc.GetLoginsRequest{URL: "https://example.com"}); err != nil {
+			b.Fatal("GetLogins", err)
+		}
+	}
+}
+
+func BenchmarkClient_SetLogin(b *testing.B) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	uuid := srv.AddEntry(gkpxctest.Entry{URL: "https://example.com", Login: "user", Password: "password"})
+
+	client := gkpxctest.NewAssociatedClient(b, srv)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err := client.SetLogin(ctx, gkpxc.SetLoginRequest{URL: "https://example.com", Login: "user", Password: "password", UUID: uuid})
+		if err != nil {
+			b.Fatal("SetLogin", err)
+		}
+	}
+}
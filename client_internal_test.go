@@ -236,3 +236,71 @@ func TestClient_Handles_signal(t *testing.T) {
 		t.Fatalf("One locked signal expected, got %+v", lockedSignals)
 	}
 }
+
+func TestClient_ConnectionLost(t *testing.T) {
+	cc, sc := net.Pipe()
+	go func() {
+		dec := json.NewDecoder(sc)
+
+		var req Message
+		dec.Decode(&req)
+		json.NewEncoder(sc).Encode(Message{
+			Action:    "change-public-keys",
+			PublicKey: bytes.Repeat([]byte{1}, KeySize),
+			Nonce:     incrementNonce(req.Nonce),
+		})
+
+		// garbage and malformed message are skipped, then connection breaks in the middle of response
+		dec.Decode(&req)
+		sc.Write([]byte("garbage\n{\"action\": 1}\n{\"action\":\"associate\""))
+		sc.Close()
+	}()
+
+	errs := make(chan error, 3)
+
+	c, err := NewClient(context.Background(), WithConn(cc), WithAsyncErrorHandler(func(err error) { errs <- err }))
+	if err != nil {
+		t.Fatalf("Got error %s, expected nil", err)
+	}
+
+	defer c.Close()
+
+	// malformed message can't be attributed to request so it doesn't fail pending one
+	if err = c.Associate(context.Background()); !errors.Is(err, ErrConnectionLost) {
+		t.Fatalf("Expected ErrConnectionLost, got %v", err)
+	}
+
+	if err = c.Associate(context.Background()); !errors.Is(err, ErrConnectionLost) {
+		t.Fatalf("Expected ErrConnectionLost, got %v", err)
+	}
+
+	invalid := 0
+	for i := 0; i < cap(errs); i++ {
+		if errors.Is(<-errs, ErrInvalidMessage) {
+			invalid++
+		}
+	}
+
+	if invalid != 2 {
+		t.Fatalf("Expected two ErrInvalidMessage passed to handler, got %d", invalid)
+	}
+}
+
+func TestClient_MessageTooLarge(t *testing.T) {
+	cc, sc := net.Pipe()
+	go func() {
+		var req Message
+		json.NewDecoder(sc).Decode(&req)
+		json.NewEncoder(sc).Encode(Message{
+			Action:    "change-public-keys",
+			PublicKey: bytes.Repeat([]byte{1}, KeySize),
+			Nonce:     incrementNonce(req.Nonce),
+			Version:   string(bytes.Repeat([]byte{'1'}, 1024)),
+		})
+	}()
+
+	_, err := NewClient(context.Background(), WithConn(cc), WithMaxMessageSize(512))
+	if !errors.Is(err, ErrConnectionLost) || !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("Unexpected error %v, expected ErrConnectionLost caused by ErrMessageTooLarge", err)
+	}
+}
@@ -6,6 +6,14 @@ type clientConfig struct {
 	customConn         net.Conn
 	errorHandlers      []func(err error)
 	lockChangeHandlers []func(locked bool)
+	messageHandlers    []func(msg Message)
+	memoryLock         bool
+	secretBytes        bool
+	tracer             func(TraceEvent)
+	traceSecrets       bool
+	observer           Observer
+	interceptors       []Interceptor
+	maxMessageSize     int
 }
 
 type ClientOption func(o *clientConfig)
@@ -30,3 +38,67 @@ func WithLockChangeHandler(handler func(locked bool)) ClientOption {
 		o.lockChangeHandlers = append(o.lockChangeHandlers, handler)
 	}
 }
+
+// WithUnsolicitedMessageHandler adds handler for messages which are not responses to requests: signals
+// (also passed to lock change handlers) and unknown ones. Fields not modeled by library are in Message.Extra.
+func WithUnsolicitedMessageHandler(handler func(msg Message)) ClientOption {
+	return func(o *clientConfig) {
+		o.messageHandlers = append(o.messageHandlers, handler)
+	}
+}
+
+// WithMemoryLock locks pages with key material in memory (mlock) to keep them out of swap.
+// Supported only on Linux, ignored on other platforms. Note that RLIMIT_MEMLOCK may be too low for it.
+func WithMemoryLock() ClientOption {
+	return func(o *clientConfig) {
+		o.memoryLock = true
+	}
+}
+
+// WithSecretBytes makes Client.GetLogins return passwords in LoginEntry.PasswordBytes instead of LoginEntry.Password.
+// Unlike strings byte slices can be wiped by caller after use.
+func WithSecretBytes() ClientOption {
+	return func(o *clientConfig) {
+		o.secretBytes = true
+	}
+}
+
+// WithTracer sets function called after every exchange with KeepassXC. Secrets (passwords, TOTP, keys)
+// in traced messages are redacted unless WithUnsafeTraceSecrets used.
+// Tracer is called synchronously so it should not block.
+func WithTracer(tracer func(TraceEvent)) ClientOption {
+	return func(o *clientConfig) {
+		o.tracer = tracer
+	}
+}
+
+// WithUnsafeTraceSecrets disables secrets redaction in trace events.
+// Unsafe: traces will contain passwords and keys, use it only for debugging on test databases.
+func WithUnsafeTraceSecrets() ClientOption {
+	return func(o *clientConfig) {
+		o.traceSecrets = true
+	}
+}
+
+// WithObserver sets instrumentation observer, i.e. to collect metrics.
+func WithObserver(observer Observer) ClientOption {
+	return func(o *clientConfig) {
+		o.observer = observer
+	}
+}
+
+// WithInterceptor adds interceptor called around every exchange with KeepassXC including handshake.
+// Interceptors are called in order they were added, first one is outermost.
+func WithInterceptor(interceptor Interceptor) ClientOption {
+	return func(o *clientConfig) {
+		o.interceptors = append(o.interceptors, interceptor)
+	}
+}
+
+// WithMaxMessageSize limits size of message received from KeepassXC (DefaultMaxMessageSize by default).
+// Larger message breaks connection with ErrMessageTooLarge.
+func WithMaxMessageSize(size int) ClientOption {
+	return func(o *clientConfig) {
+		o.maxMessageSize = size
+	}
+}
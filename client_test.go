@@ -0,0 +1,97 @@
+package gkpxc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/gkpxctest"
+)
+
+func TestClient_GeneratePassword(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	answers := make(chan string)
+	srv.SetPasswordGenerator(func() (string, bool) {
+		password, ok := <-answers
+		return password, ok && password != ""
+	})
+
+	ctx := context.Background()
+	client := gkpxctest.NewAssociatedClient(t, srv)
+
+	t.Run("accepted", func(t *testing.T) {
+		go func() { answers <- "generated" }()
+
+		password, err := client.GeneratePassword(ctx)
+		if err != nil {
+			t.Fatal("GeneratePassword", err)
+		}
+
+		if password != "generated" {
+			t.Fatalf("Got password %s, expected generated", password)
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		go func() { answers <- "" }()
+
+		_, err := client.GeneratePassword(ctx)
+		if !gkpxc.IsErrorCode(err, gkpxc.ErrCodeActionCancelledOrDenied) {
+			t.Fatalf("Unexpected error %v, expected ErrCodeActionCancelledOrDenied", err)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		if _, err := client.GeneratePassword(cancelCtx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Unexpected error %v, expected context.Canceled", err)
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		// association test passes, request waits for dialog
+		if _, err := client.GeneratePassword(timeoutCtx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Unexpected error %v, expected context.DeadlineExceeded", err)
+		}
+
+		// late response must be dropped
+		answers <- "late"
+
+		go func() { answers <- "next" }()
+
+		password, err := client.GeneratePassword(ctx)
+		if err != nil {
+			t.Fatal("GeneratePassword", err)
+		}
+
+		if password != "next" {
+			t.Fatalf("Got password %s, expected next", password)
+		}
+	})
+}
+
+func TestClient_GeneratePassword_legacy(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	srv.SetVersion("2.6.6")
+
+	ctx := context.Background()
+	client := gkpxctest.NewAssociatedClient(t, srv)
+
+	password, err := client.GeneratePassword(ctx)
+	if err != nil {
+		t.Fatal("GeneratePassword", err)
+	}
+
+	if len(password) != 32 {
+		t.Fatalf("Unexpected password %s", password)
+	}
+}
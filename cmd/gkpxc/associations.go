@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/99designs/keyring"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/internal/bootstrap"
+)
+
+const associationsUsage = `Usage: gkpxc associations <export|import|rotate> [flags] [file]
+
+Export writes stored association credentials as JSON to file or stdout.
+Import reads association credentials exported by gkpxc or KeePassXC-Browser from file or stdin.
+Rotate replaces association with opened database by a new one.
+`
+
+func associations(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, associationsUsage)
+		os.Exit(2)
+	}
+
+	var (
+		svc, storePath, keyFile string
+		force                   bool
+	)
+
+	fs := flag.NewFlagSet("associations "+args[0], flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), associationsUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&svc, "service", service, "keyring `service` name, i.e. docker-credential-keepassxc to use docker helper associations")
+	fs.StringVar(&storePath, "store", "", "use encrypted file store at `path` instead of OS keyring")
+	fs.StringVar(&keyFile, "key-file", "", "key `file` for encrypted file store, passphrase prompted with GKPXC_ASKPASS command if not set")
+	if args[0] == "import" {
+		fs.BoolVar(&force, "force", false, "overwrite existing associations")
+	}
+	_ = fs.Parse(args[1:])
+
+	var (
+		kr  keyring.Keyring
+		err error
+	)
+
+	if storePath != "" {
+		kr, err = bootstrap.OpenFileStore(svc, bootstrap.FileStoreOptions{
+			Path:       storePath,
+			KeyFile:    keyFile,
+			AskPassCmd: os.Getenv("GKPXC_ASKPASS"),
+		})
+	} else {
+		kr, err = bootstrap.SetupKeyring(svc, os.Getenv("GKPXC_ASKPASS"))
+	}
+
+	if err != nil {
+		return fmt.Errorf("keyring for private key open failed: %w", err)
+	}
+
+	if closer, ok := kr.(io.Closer); ok {
+		defer closer.Close() // file store wipes key
+	}
+
+	switch args[0] {
+	case "export":
+		return exportAssociations(kr, fs.Arg(0))
+	case "import":
+		return importAssociations(kr, fs.Arg(0), force)
+	case "rotate":
+		return rotateAssociation(kr)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown associations command %q\n\n%s", args[0], associationsUsage)
+		os.Exit(2)
+	}
+
+	return nil
+}
+
+func exportAssociations(kr keyring.Keyring, path string) error {
+	keys, err := kr.Keys()
+	if err != nil {
+		return fmt.Errorf("list associations: %w", err)
+	}
+
+	list := make([]gkpxc.AssociationCredentials, 0, len(keys))
+
+	for _, key := range keys {
+		item, err := kr.Get(key)
+		if err != nil {
+			return fmt.Errorf("get association %s: %w", key, err)
+		}
+
+		var cred gkpxc.AssociationCredentials
+		if err = cred.UnmarshalText(item.Data); err != nil {
+			log.Printf("Skipping %s: %s", key, err)
+			continue
+		}
+
+		list = append(list, cred)
+	}
+
+	serialized, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("serialize associations: %w", err)
+	}
+
+	serialized = append(serialized, '\n')
+
+	if path == "" {
+		_, err = os.Stdout.Write(serialized)
+		return err
+	}
+
+	return os.WriteFile(path, serialized, 0o600)
+}
+
+func rotateAssociation(kr keyring.Keyring) error {
+	ctx := context.Background()
+	connector := bootstrap.Connector{Keyring: kr, ClientOptions: clientOptions}
+
+	client, err := connector.Connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer client.Close()
+
+	old := client.AssociationCredentials()
+
+	if err = connector.Rotate(ctx, client); err != nil {
+		return err
+	}
+
+	log.Printf("Association %s replaced by %s, remove old one in KeepassXC database settings",
+		old.ID, client.AssociationCredentials().ID)
+
+	return nil
+}
+
+func importAssociations(kr keyring.Keyring, path string, force bool) error {
+	var (
+		content []byte
+		err     error
+	)
+
+	if path == "" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("read associations: %w", err)
+	}
+
+	list, err := gkpxc.UnmarshalAssociations(content)
+	if err != nil {
+		return fmt.Errorf("parse associations: %w", err)
+	}
+
+	imported := 0
+
+	for _, cred := range list {
+		if cred.Hash == "" {
+			log.Printf("Skipping association %s: no database hash", cred.ID)
+			continue
+		}
+
+		_, err = kr.Get(cred.Hash)
+		switch {
+		case errors.Is(err, keyring.ErrKeyNotFound):
+		case errors.Is(err, nil) && force:
+		case errors.Is(err, nil):
+			log.Printf("Skipping association %s: database %s already associated, use -force to overwrite", cred.ID, cred.Hash)
+			continue
+		default:
+			return fmt.Errorf("get association %s: %w", cred.Hash, err)
+		}
+
+		serialized, err := cred.MarshalText()
+		if err != nil {
+			return fmt.Errorf("serialize association %s: %w", cred.ID, err)
+		}
+
+		if err = kr.Set(keyring.Item{Key: cred.Hash, Data: serialized}); err != nil {
+			return fmt.Errorf("store association %s: %w", cred.ID, err)
+		}
+
+		imported++
+	}
+
+	log.Printf("Imported %d of %d associations", imported, len(list))
+
+	return nil
+}
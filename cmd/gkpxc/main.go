@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/envrun"
+	"github.com/xakep666/gkpxc/internal/bootstrap"
+)
+
+const service = "gkpxc"
+
+// clientOptions are passed to every KeepassXC client.
+var clientOptions []gkpxc.ClientOption
+
+const usage = `Usage: gkpxc [--debug] <command> [arguments]
+
+Flags:
+  --debug       print KeepassXC protocol trace with redacted secrets to stderr
+
+Commands:
+  run           run command with environment variables taken from KeepassXC
+  associations  export or import association credentials
+`
+
+type stringsFlag []string
+
+func (s *stringsFlag) String() string { return strings.Join(*s, ",") }
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("gkpxc: ")
+
+	args, debug := bootstrap.ParseDebugFlag(os.Args[1:])
+	if debug {
+		clientOptions = bootstrap.DebugOptions(os.Stderr)
+	}
+
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+
+	switch args[0] {
+	case "run":
+		err = run(args[1:])
+	case "associations":
+		err = associations(args[1:])
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", args[0], usage)
+		os.Exit(2)
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(err, nil):
+	case errors.As(err, &exitErr):
+		os.Exit(envrun.ExitCode(exitErr))
+	default:
+		log.Fatalln(err)
+	}
+}
+
+func run(args []string) error {
+	var (
+		envs, envFiles stringsFlag
+		mask           bool
+	)
+
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gkpxc run [flags] -- command [arguments]")
+		fs.PrintDefaults()
+	}
+	fs.Var(&envs, "env", "environment variable `NAME=VALUE`, value may be a reference like keepassxc://[login@]host#field (repeatable)")
+	fs.Var(&envFiles, "env-file", "dotenv-style `file` with variables (repeatable)")
+	fs.BoolVar(&mask, "mask", false, "replace secret values in command output with "+envrun.Mask)
+	_ = fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var vars []envrun.Variable
+
+	for _, file := range envFiles {
+		fileVars, err := readEnvFile(file)
+		if err != nil {
+			return err
+		}
+
+		vars = append(vars, fileVars...)
+	}
+
+	for _, env := range envs {
+		v, err := envrun.ParseVariable(env)
+		if err != nil {
+			return err
+		}
+
+		vars = append(vars, v)
+	}
+
+	ctx := context.Background()
+
+	kr, err := bootstrap.SetupKeyring(service, os.Getenv("GKPXC_ASKPASS"))
+	if err != nil {
+		return fmt.Errorf("keyring for private key open failed: %w", err)
+	}
+
+	client, err := bootstrap.Connect(ctx, kr, clientOptions...)
+	if err != nil {
+		return err
+	}
+
+	defer client.Close()
+
+	runner := envrun.Runner{
+		Resolver: &envrun.Resolver{Client: client},
+		Mask:     mask,
+	}
+
+	return runner.Run(ctx, vars, fs.Arg(0), fs.Args()[1:]...)
+}
+
+func readEnvFile(path string) ([]envrun.Variable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	vars, err := envrun.ParseDotEnv(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return vars, nil
+}
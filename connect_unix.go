@@ -9,6 +9,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"syscall"
 )
 
 var lookupPaths = []string{
@@ -20,11 +21,12 @@ var lookupPaths = []string{
 func connect(ctx context.Context) (net.Conn, error) {
 	var lastErr error
 	var socketPath string
+	var info os.FileInfo
 
 lookup:
 	for _, dir := range lookupPaths {
 		socketPath = filepath.Join(dir, SocketName)
-		_, lastErr = os.Stat(socketPath)
+		info, lastErr = os.Stat(socketPath)
 		switch {
 		case errors.Is(lastErr, nil):
 			break lookup
@@ -38,5 +40,69 @@ lookup:
 		return nil, fmt.Errorf("socket lookup: %s", lastErr)
 	}
 
-	return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+	if err := checkSocketFile(socketPath, info); err != nil {
+		return nil, err
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkPeer(conn.(*net.UnixConn)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// checkSocketFile ensures that socket is owned by current user and not accessible by others.
+// KeepassXC creates socket with user-only access.
+func checkSocketFile(path string, info os.FileInfo) error {
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%w: %s is not a socket", ErrInsecureSocket, path)
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%w: %s owned by uid %d, expected %d", ErrInsecureSocket, path, stat.Uid, os.Getuid())
+	}
+
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return fmt.Errorf("%w: %s has mode %#o, access by other users allowed", ErrInsecureSocket, path, perm)
+	}
+
+	return nil
+}
+
+// checkPeer ensures that process listening socket runs as current user if peer credentials are supported.
+func checkPeer(conn *net.UnixConn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("peer credentials: %w", err)
+	}
+
+	var (
+		uid       int
+		supported bool
+		credErr   error
+	)
+
+	err = raw.Control(func(fd uintptr) {
+		uid, supported, credErr = peerUID(int(fd))
+	})
+	if err == nil {
+		err = credErr
+	}
+
+	switch {
+	case err != nil:
+		return fmt.Errorf("peer credentials: %w", err)
+	case !supported:
+		return nil
+	case uid != os.Getuid():
+		return fmt.Errorf("%w: socket served by process of uid %d, expected %d", ErrInsecureSocket, uid, os.Getuid())
+	default:
+		return nil
+	}
 }
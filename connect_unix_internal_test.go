@@ -0,0 +1,105 @@
+//go:build !windows
+
+package gkpxc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConnect_SocketChecks(t *testing.T) {
+	dir := t.TempDir()
+
+	oldLookupPaths := lookupPaths
+	lookupPaths = []string{dir}
+	t.Cleanup(func() { lookupPaths = oldLookupPaths })
+
+	socketPath := filepath.Join(dir, SocketName)
+
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal("Listen", err)
+	}
+
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			conn.Close()
+		}
+	}()
+
+	if err = os.Chmod(socketPath, 0o777); err != nil {
+		t.Fatal("Chmod", err)
+	}
+
+	if _, err = connect(context.Background()); !errors.Is(err, ErrInsecureSocket) {
+		t.Fatalf("Unexpected error %v, expected ErrInsecureSocket", err)
+	}
+
+	if err = os.Chmod(socketPath, 0o700); err != nil {
+		t.Fatal("Chmod", err)
+	}
+
+	conn, err := connect(context.Background())
+	if err != nil {
+		t.Fatal("Connect", err)
+	}
+
+	conn.Close()
+}
+
+func TestCheckSocketFile_NotSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SocketName)
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal("Write", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal("Stat", err)
+	}
+
+	if err = checkSocketFile(path, info); !errors.Is(err, ErrInsecureSocket) {
+		t.Fatalf("Unexpected error %v, expected ErrInsecureSocket", err)
+	}
+}
+
+func TestNewClient_InsecureSocket(t *testing.T) {
+	dir := t.TempDir()
+
+	oldLookupPaths := lookupPaths
+	lookupPaths = []string{dir}
+	t.Cleanup(func() { lookupPaths = oldLookupPaths })
+
+	socketPath := filepath.Join(dir, SocketName)
+
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal("Listen", err)
+	}
+
+	defer l.Close()
+
+	if err = os.Chmod(socketPath, 0o777); err != nil {
+		t.Fatal("Chmod", err)
+	}
+
+	_, err = NewClient(context.Background())
+	if !errors.Is(err, ErrInsecureSocket) {
+		t.Fatalf("Unexpected error %v, expected ErrInsecureSocket", err)
+	}
+
+	if !errors.Is(err, ErrConnectFailed) {
+		t.Fatalf("Unexpected error %v, expected ErrConnectFailed", err)
+	}
+}
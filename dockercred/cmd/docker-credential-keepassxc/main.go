@@ -1,18 +1,35 @@
 package main
 
 import (
+	"io"
 	"log"
+	"os"
 
 	"github.com/docker/docker-credential-helpers/credentials"
 
 	"github.com/xakep666/gkpxc/dockercred"
+	"github.com/xakep666/gkpxc/internal/bootstrap"
 )
 
 func main() {
-	kr, err := dockercred.SetupKeyring("docker-credential-keepassxc")
+	args, debug := bootstrap.ParseDebugFlag(os.Args[1:])
+	os.Args = append(os.Args[:1], args...) // credentials.Serve reads action from os.Args
+
+	cfg, err := dockercred.LoadConfig()
+	if err != nil {
+		log.Fatalln("Config load failed:", err)
+	}
+
+	cfg.Debug = cfg.Debug || debug
+
+	kr, err := dockercred.OpenStore("docker-credential-keepassxc", cfg)
 	if err != nil {
 		log.Fatalln("Keyring for private key open failed:", err)
 	}
 
-	credentials.Serve(&dockercred.KeepassXCHelper{Keyring: kr})
+	if closer, ok := kr.(io.Closer); ok {
+		defer closer.Close() // file store wipes key
+	}
+
+	credentials.Serve(&dockercred.KeepassXCHelper{Keyring: kr, Config: cfg})
 }
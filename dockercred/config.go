@@ -0,0 +1,259 @@
+package dockercred
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker-credential-helpers/credentials"
+)
+
+// Environment variables overriding configuration file values.
+const (
+	EnvConfig         = "DOCKER_CREDENTIAL_KEEPASSXC_CONFIG"
+	EnvGroup          = "DOCKER_CREDENTIAL_KEEPASSXC_GROUP"
+	EnvSchemes        = "DOCKER_CREDENTIAL_KEEPASSXC_SCHEMES"
+	EnvV2Suffix       = "DOCKER_CREDENTIAL_KEEPASSXC_V2_SUFFIX"
+	EnvUsername       = "DOCKER_CREDENTIAL_KEEPASSXC_USERNAME"
+	EnvTokenTTL       = "DOCKER_CREDENTIAL_KEEPASSXC_TOKEN_TTL"
+	EnvTimeout        = "DOCKER_CREDENTIAL_KEEPASSXC_TIMEOUT"
+	EnvNonInteractive = "DOCKER_CREDENTIAL_KEEPASSXC_NON_INTERACTIVE"
+	EnvStore          = "DOCKER_CREDENTIAL_KEEPASSXC_STORE"
+	EnvStoreKeyFile   = "DOCKER_CREDENTIAL_KEEPASSXC_STORE_KEY_FILE"
+	EnvStoreCred      = "DOCKER_CREDENTIAL_KEEPASSXC_STORE_CREDENTIAL"
+	EnvAskPass        = "DOCKER_CREDENTIAL_KEEPASSXC_ASKPASS"
+	EnvDebug          = "DOCKER_CREDENTIAL_KEEPASSXC_DEBUG"
+	configFile        = "config.json"
+	configFolder      = "docker-credential-keepassxc"
+)
+
+// defaultNonInteractiveTimeout is used in non-interactive mode if timeout is not set.
+const defaultNonInteractiveTimeout = 10 * time.Second
+
+// Config configures KeepassXCHelper.
+// Entry titles are not configurable: KeepassXC names entries created by browser protocol by URL host
+// and provides no way to rename them.
+type Config struct {
+	// Group is a path of group for new entries, i.e. "Infra/Docker". Default is credentials.CredsLabel.
+	Group string `json:"group,omitempty"`
+
+	// Lookup contains rules to find entries for registry.
+	Lookup LookupConfig `json:"lookup"`
+
+	// Username is preferred user name if multiple entries found for registry.
+	Username string `json:"username,omitempty"`
+
+	// Usernames maps registry host to preferred user name. Overrides Username.
+	Usernames map[string]string `json:"usernames,omitempty"`
+
+	// IdentityTokenTTL is a lifetime of identity tokens issued by registries. Expired tokens are not returned.
+	// It's used only if token is not JWT with expiration time. Zero means that tokens don't expire.
+	IdentityTokenTTL Duration `json:"identityTokenTTL,omitempty"`
+
+	// Timeout limits every helper operation duration including waiting for user confirmations. Zero means no limit.
+	Timeout Duration `json:"timeout,omitempty"`
+
+	// NonInteractive makes helper fail instead of waiting for database unlock and association confirmation.
+	// Default timeout in this mode is 10s.
+	NonInteractive bool `json:"nonInteractive,omitempty"`
+
+	// NoReassociation disables new association request if stored one was removed in KeepassXC.
+	// Stored association credentials are discarded anyway.
+	NoReassociation bool `json:"noReassociation,omitempty"`
+
+	// StateFile is a file used to remember entries stored by helper. Default is StatePath.
+	// Remembered entries are preferred by lookup. If empty entries remembered only in memory.
+	StateFile string `json:"stateFile,omitempty"`
+
+	// Debug enables KeepassXC protocol trace with redacted secrets to stderr.
+	Debug bool `json:"debug,omitempty"`
+
+	// Store configures where association credentials are kept.
+	Store StoreConfig `json:"store"`
+}
+
+// Association store types.
+const (
+	StoreKeyring = "keyring"
+	StoreFile    = "file"
+)
+
+// StoreConfig configures association credentials store.
+type StoreConfig struct {
+	// Type is StoreKeyring (OS keyring) or StoreFile (encrypted file).
+	// Default is StoreFile if key file or credential set, StoreKeyring otherwise.
+	Type string `json:"type,omitempty"`
+
+	// Path is a file store path. Default is "<user config dir>/docker-credential-keepassxc/associations.json".
+	Path string `json:"path,omitempty"`
+
+	// KeyFile is a path to file with file store key.
+	KeyFile string `json:"keyFile,omitempty"`
+
+	// Credential is a name of systemd credential with file store key.
+	Credential string `json:"credential,omitempty"`
+}
+
+// LookupConfig contains rules to find entries for registry.
+type LookupConfig struct {
+	// Schemes are tried in order to build lookup URL for registry without scheme. Default is ["https"].
+	// First scheme is used to store new entries.
+	Schemes []string `json:"schemes,omitempty"`
+
+	// V2Suffix enables lookup of URL with and without "/v2/" path.
+	V2Suffix bool `json:"v2Suffix,omitempty"`
+
+	// NoDockerHubAliases disables lookup of Docker Hub credentials by all its aliases
+	// ("index.docker.io/v1/", "docker.io", "registry-1.docker.io").
+	NoDockerHubAliases bool `json:"noDockerHubAliases,omitempty"`
+}
+
+// ConfigPath returns default configuration file path. It may be overridden with EnvConfig variable.
+func ConfigPath() (string, error) {
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path, nil
+	}
+
+	cfgDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cfgDir, configFolder, configFile), nil
+}
+
+// LoadConfig reads configuration from file (see ConfigPath) and applies environment variables.
+// Missing file is not an error.
+func LoadConfig() (Config, error) {
+	var cfg Config
+
+	path, err := ConfigPath()
+	if err != nil {
+		return Config{}, fmt.Errorf("config path: %w", err)
+	}
+
+	content, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, nil):
+		if err = json.Unmarshal(content, &cfg); err != nil {
+			return Config{}, fmt.Errorf("parse config %s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+	default:
+		return Config{}, fmt.Errorf("read config: %w", err)
+	}
+
+	if cfg.StateFile == "" {
+		if cfg.StateFile, err = StatePath(); err != nil {
+			return Config{}, fmt.Errorf("state path: %w", err)
+		}
+	}
+
+	if group := os.Getenv(EnvGroup); group != "" {
+		cfg.Group = group
+	}
+
+	if schemes := os.Getenv(EnvSchemes); schemes != "" {
+		cfg.Lookup.Schemes = strings.Split(schemes, ",")
+	}
+
+	if v2Suffix := os.Getenv(EnvV2Suffix); v2Suffix != "" {
+		cfg.Lookup.V2Suffix = parseBool(v2Suffix)
+	}
+
+	if username := os.Getenv(EnvUsername); username != "" {
+		cfg.Username = username
+	}
+
+	if ttl := os.Getenv(EnvTokenTTL); ttl != "" {
+		parsed, err := time.ParseDuration(ttl)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", EnvTokenTTL, err)
+		}
+
+		cfg.IdentityTokenTTL = Duration(parsed)
+	}
+
+	if timeout := os.Getenv(EnvTimeout); timeout != "" {
+		parsed, err := time.ParseDuration(timeout)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", EnvTimeout, err)
+		}
+
+		cfg.Timeout = Duration(parsed)
+	}
+
+	if nonInteractive := os.Getenv(EnvNonInteractive); nonInteractive != "" {
+		cfg.NonInteractive = parseBool(nonInteractive)
+	}
+
+	if debug := os.Getenv(EnvDebug); debug != "" {
+		cfg.Debug = parseBool(debug)
+	}
+
+	if store := os.Getenv(EnvStore); store != "" {
+		cfg.Store.Type = store
+	}
+
+	if keyFile := os.Getenv(EnvStoreKeyFile); keyFile != "" {
+		cfg.Store.KeyFile = keyFile
+	}
+
+	if credential := os.Getenv(EnvStoreCred); credential != "" {
+		cfg.Store.Credential = credential
+	}
+
+	return cfg, nil
+}
+
+func parseBool(s string) bool {
+	return s == "1" || strings.EqualFold(s, "true")
+}
+
+func (c Config) group() string {
+	if c.Group == "" {
+		return credentials.CredsLabel
+	}
+
+	return c.Group
+}
+
+func (c Config) schemes() []string {
+	if len(c.Lookup.Schemes) == 0 {
+		return []string{"https"}
+	}
+
+	return c.Lookup.Schemes
+}
+
+func (c Config) preferredUsername(host string) string {
+	if username, ok := c.Usernames[host]; ok {
+		return username
+	}
+
+	return c.Username
+}
+
+func (c Config) timeout() time.Duration {
+	if c.Timeout == 0 && c.NonInteractive {
+		return defaultNonInteractiveTimeout
+	}
+
+	return time.Duration(c.Timeout)
+}
+
+func (c StoreConfig) storeType() string {
+	if c.Type == "" && (c.KeyFile != "" || c.Credential != "") {
+		return StoreFile
+	}
+
+	if c.Type == "" {
+		return StoreKeyring
+	}
+
+	return c.Type
+}
@@ -2,159 +2,314 @@ package dockercred
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"net/url"
+	"os"
+	"time"
 
 	"github.com/99designs/keyring"
 	"github.com/docker/docker-credential-helpers/credentials"
 
 	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/internal/bootstrap"
+)
+
+var (
+	// ErrAmbiguousCredentials returned if multiple entries match registry and it's not possible to choose one.
+	ErrAmbiguousCredentials = errors.New("multiple credentials found for registry, set preferred username in config")
+
+	// ErrDeleteDenied returned if deletion was denied in KeepassXC.
+	ErrDeleteDenied = errors.New("entry deletion denied in KeepassXC")
 )
 
 type KeepassXCHelper struct {
+	// Keyring stores association credentials.
 	Keyring keyring.Keyring
+	Config  Config
+
+	// NewClient creates KeepassXC client. Default is gkpxc.NewClient.
+	NewClient func(ctx context.Context, opts ...gkpxc.ClientOption) (*gkpxc.Client, error)
+
+	// ClientOptions are passed to NewClient.
+	ClientOptions []gkpxc.ClientOption
 
 	client *gkpxc.Client
+	index  *entryIndex
 }
 
 func (h *KeepassXCHelper) Add(credentials *credentials.Credentials) error {
-	if err := h.initialize(); err != nil {
-		return err
+	ctx, cancel := h.context()
+	defer cancel()
+
+	return classifyError(h.add(ctx, credentials))
+}
+
+func (h *KeepassXCHelper) Delete(serverURL string) error {
+	ctx, cancel := h.context()
+	defer cancel()
+
+	return classifyError(h.delete(ctx, serverURL))
+}
+
+func (h *KeepassXCHelper) Get(serverURL string) (string, string, error) {
+	ctx, cancel := h.context()
+	defer cancel()
+
+	username, secret, err := h.get(ctx, serverURL)
+
+	return username, secret, classifyError(err)
+}
+
+func (h *KeepassXCHelper) List() (map[string]string, error) {
+	// keepass doesn't allow credentials listing
+	return nil, nil
+}
+
+// Close closes KeepassXC client if it was created.
+func (h *KeepassXCHelper) Close() error {
+	if h.client == nil {
+		return nil
+	}
+
+	err := h.client.Close()
+	h.client = nil
+
+	return err
+}
+
+// context returns context for single helper operation.
+func (h *KeepassXCHelper) context() (context.Context, context.CancelFunc) {
+	if timeout := h.Config.timeout(); timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
 	}
 
-	ctx := context.Background()
+	return context.WithCancel(context.Background())
+}
+
+func (h *KeepassXCHelper) add(ctx context.Context, credentials *credentials.Credentials) error {
+	if err := h.initialize(ctx); err != nil {
+		return err
+	}
 
-	group, err := h.getOrCreateGroup()
+	group, err := h.getOrCreateGroup(ctx)
 	if err != nil {
 		return err
 	}
 
-	var entryUUID string
-	if existingLogins, err := h.client.GetLogins(ctx, gkpxc.GetLoginsRequest{URL: credentials.ServerURL}); err == nil {
-		for _, entry := range existingLogins.Entries {
-			if entry.Login == credentials.Username {
-				entryUUID = entry.UUID
-			}
+	serverURL := h.normalizeURL(credentials.ServerURL)
+	stored := storedEntry{Username: credentials.Username}
+
+	if credentials.Username == identityTokenUsername {
+		stored.Expires = tokenExpiry(credentials.Secret, time.Duration(h.Config.IdentityTokenTTL), time.Now())
+	}
+
+	// update only entry with same user name, otherwise new one created
+	entry, err := h.findEntry(ctx, credentials.ServerURL, true, stored)
+	switch {
+	case errors.Is(err, nil):
+		if entry.Login == credentials.Username {
+			stored.UUID = entry.UUID
 		}
+	case errors.Is(err, ErrAmbiguousCredentials), credentialsNotFound(err):
+	default:
+		return err
 	}
 
-	return h.client.SetLogin(ctx, gkpxc.SetLoginRequest{
-		URL:       addHTTPS(credentials.ServerURL),
+	err = h.client.SetLogin(ctx, gkpxc.SetLoginRequest{
+		URL:       serverURL,
 		Login:     credentials.Username,
 		Password:  credentials.Secret,
 		Group:     group.Name,
 		GroupUUID: group.UUID,
-		UUID:      entryUUID,
+		UUID:      stored.UUID,
 	})
+	if err != nil {
+		return err
+	}
+
+	if stored.UUID == "" {
+		// remember created entry if it can be determined
+		if entry, err := h.findEntry(ctx, credentials.ServerURL, true, stored); err == nil && entry.Login == credentials.Username {
+			stored.UUID = entry.UUID
+		}
+	}
+
+	return h.index.set(serverURL, stored)
 }
 
-func (h *KeepassXCHelper) Delete(serverURL string) error {
-	if err := h.initialize(); err != nil {
+func (h *KeepassXCHelper) delete(ctx context.Context, serverURL string) error {
+	if err := h.initialize(ctx); err != nil {
+		return err
+	}
+
+	normalizedURL := h.normalizeURL(serverURL)
+
+	entry, err := h.findEntry(ctx, serverURL, true, h.index.get(normalizedURL))
+	if err != nil {
 		return err
 	}
 
 	var keepassError *gkpxc.ErrorResponse
 
-	logins, err := h.client.GetLogins(context.Background(), gkpxc.GetLoginsRequest{URL: serverURL})
+	err = h.client.DeleteEntry(ctx, gkpxc.DeleteEntryRequest{UUID: entry.UUID})
 	switch {
 	case errors.Is(err, nil):
-		return h.client.DeleteEntry(context.Background(), gkpxc.DeleteEntryRequest{UUID: logins.Entries[0].UUID})
-	case errors.As(err, &keepassError) && keepassError.Code == 15:
-		return credentials.NewErrCredentialsNotFound()
+		return h.index.remove(normalizedURL)
+	case errors.As(err, &keepassError) &&
+		(keepassError.Code == gkpxc.ErrCodeActionCancelledOrDenied || keepassError.Code == 0):
+		return fmt.Errorf("%w: %s", ErrDeleteDenied, err)
 	default:
 		return err
 	}
 }
 
-func (h *KeepassXCHelper) Get(serverURL string) (string, string, error) {
-	if err := h.initialize(); err != nil {
+func (h *KeepassXCHelper) get(ctx context.Context, serverURL string) (string, string, error) {
+	if err := h.initialize(ctx); err != nil {
 		return "", "", err
 	}
 
-	var keepassError *gkpxc.ErrorResponse
+	stored := h.index.get(h.normalizeURL(serverURL))
 
-	logins, err := h.client.GetLogins(context.Background(), gkpxc.GetLoginsRequest{URL: addHTTPS(serverURL)})
-	switch {
-	case errors.Is(err, nil):
-		return logins.Entries[0].Login, logins.Entries[0].Password, nil
-	case errors.As(err, &keepassError) && keepassError.Code == 15:
-		return "", "", credentials.NewErrCredentialsNotFound()
-	default:
+	entry, err := h.findEntry(ctx, serverURL, false, stored)
+	if err != nil {
 		return "", "", err
 	}
+
+	if !isIdentityToken(entry) {
+		return entry.Login, entry.Password, nil
+	}
+
+	if identityTokenExpired(entry, stored, time.Now()) {
+		return "", "", credentials.NewErrCredentialsNotFound()
+	}
+
+	return identityTokenUsername, entry.Password, nil
 }
 
-func (h *KeepassXCHelper) List() (map[string]string, error) {
-	// keepass doesn't allow credentials listing
-	return nil, nil
+func (h *KeepassXCHelper) normalizeURL(serverURL string) string {
+	return normalizeURL(serverURL, h.Config.schemes()[0]).String()
 }
 
-func (h *KeepassXCHelper) initialize() error {
-	if h.client != nil {
-		return nil
+// findEntry looks up entry for registry. If exact is set only normalized URL (same as used by Add) is looked up,
+// otherwise lookup rules from config used.
+func (h *KeepassXCHelper) findEntry(ctx context.Context, serverURL string, exact bool, stored storedEntry) (gkpxc.LoginEntry, error) {
+	lookupURLs := []string{h.normalizeURL(serverURL)}
+	if !exact {
+		lookupURLs = h.Config.lookupURLs(serverURL)
 	}
 
-	ctx := context.Background()
+	preferredUsername := h.Config.preferredUsername(normalizeURL(serverURL, h.Config.schemes()[0]).Host)
 
-	client, err := gkpxc.NewClient(ctx)
-	if err != nil {
-		return fmt.Errorf("keepassxc connect failed: %w", err)
-	}
+	for _, lookupURL := range lookupURLs {
+		logins, err := h.client.GetLogins(ctx, gkpxc.GetLoginsRequest{URL: lookupURL})
+		switch {
+		case errors.Is(err, nil):
+		case gkpxc.IsErrorCode(err, gkpxc.ErrCodeNoLoginsFound):
+			continue
+		default:
+			return gkpxc.LoginEntry{}, err
+		}
 
-	dbHash, err := client.GetDatabaseHash(ctx, true)
-	if err != nil {
-		return fmt.Errorf("get database hash failed: %w", err)
+		if len(logins.Entries) == 0 {
+			continue
+		}
+
+		return chooseEntry(logins.Entries, stored, preferredUsername)
 	}
 
-	secret, err := h.Keyring.Get(dbHash.Hash)
-	switch {
-	case errors.Is(err, nil):
-		var cred gkpxc.AssociationCredentials
-		if err = json.Unmarshal(secret.Data, &cred); err == nil {
-			client.SetAssociationCredentials(&cred)
-			break
+	return gkpxc.LoginEntry{}, credentials.NewErrCredentialsNotFound()
+}
+
+// chooseEntry chooses entry by stored UUID, then by last stored user name, then by preferred user name.
+func chooseEntry(entries []gkpxc.LoginEntry, stored storedEntry, preferredUsername string) (gkpxc.LoginEntry, error) {
+	if stored.UUID != "" {
+		for _, entry := range entries {
+			if entry.UUID == stored.UUID {
+				return entry, nil
+			}
 		}
+	}
 
-		fallthrough
-	case errors.Is(err, keyring.ErrKeyNotFound):
-		if err = client.Associate(ctx); err != nil {
-			return fmt.Errorf("association failed: %w", err)
+	for _, username := range []string{stored.Username, preferredUsername} {
+		if username == "" {
+			continue
 		}
 
-		serialized, err := json.Marshal(client.AssociationCredentials())
-		if err != nil {
-			return fmt.Errorf("serialize association credentials failed: %w", err)
+		var matched []gkpxc.LoginEntry
+		for _, entry := range entries {
+			if entry.Login == username {
+				matched = append(matched, entry)
+			}
 		}
 
-		if err = h.Keyring.Set(keyring.Item{Key: dbHash.Hash, Data: serialized}); err != nil {
-			return fmt.Errorf("store association credentials failed: %w", err)
+		switch len(matched) {
+		case 0:
+		case 1:
+			return matched[0], nil
+		default:
+			return gkpxc.LoginEntry{}, fmt.Errorf("%w: %d entries with user %s", ErrAmbiguousCredentials, len(matched), username)
 		}
-	default:
-		return fmt.Errorf("association key get failed: %w", err)
+	}
+
+	if len(entries) == 1 {
+		return entries[0], nil
+	}
+
+	return gkpxc.LoginEntry{}, fmt.Errorf("%w: %d entries", ErrAmbiguousCredentials, len(entries))
+}
+
+// credentialsNotFound is used where credentials package is shadowed.
+func credentialsNotFound(err error) bool {
+	return credentials.IsErrCredentialsNotFound(err)
+}
+
+func (h *KeepassXCHelper) initialize(ctx context.Context) error {
+	if h.client != nil {
+		return nil
+	}
+
+	index, err := loadEntryIndex(h.Config.StateFile)
+	if err != nil {
+		return err
+	}
+
+	connector := bootstrap.Connector{
+		Keyring:        h.Keyring,
+		NonInteractive: h.Config.NonInteractive,
+		NewClient:      h.NewClient,
+		ClientOptions:  h.ClientOptions,
+	}
+
+	if h.Config.Debug {
+		connector.ClientOptions = append(bootstrap.DebugOptions(os.Stderr), connector.ClientOptions...)
+	}
+
+	if h.Config.NoReassociation {
+		connector.ConfirmReassociation = func(context.Context, string) bool { return false }
+	}
+
+	client, err := connector.Connect(ctx)
+	if err != nil {
+		return err
 	}
 
 	h.client = client
+	h.index = index
 
 	return nil
 }
 
-func (h *KeepassXCHelper) getOrCreateGroup() (gkpxc.DatabaseGroup, error) {
-	ctx := context.Background()
-
+func (h *KeepassXCHelper) getOrCreateGroup(ctx context.Context) (gkpxc.DatabaseGroup, error) {
 	groups, err := h.client.GetDatabaseGroups(ctx)
 	if err != nil {
 		return gkpxc.DatabaseGroup{}, fmt.Errorf("get database groups failed: %w", err)
 	}
 
-	for _, group := range groups.Groups.Groups {
-		if group.Name == credentials.CredsLabel {
-			return group, nil
-		}
+	if group, ok := groups.FindGroup(h.Config.group()); ok {
+		return group, nil
 	}
 
-	group, err := h.client.CreateNewGroup(ctx, gkpxc.CreateNewGroupRequest{Name: credentials.CredsLabel})
+	group, err := h.client.CreateNewGroup(ctx, gkpxc.CreateNewGroupRequest{Name: h.Config.group()})
 	if err != nil {
 		return gkpxc.DatabaseGroup{}, fmt.Errorf("create group failed: %w", err)
 	}
@@ -164,12 +319,3 @@ func (h *KeepassXCHelper) getOrCreateGroup() (gkpxc.DatabaseGroup, error) {
 		UUID: group.UUID,
 	}, nil
 }
-
-func addHTTPS(strURL string) string {
-	// to correctly name and query records we should add scheme
-	if u, err := url.Parse(strURL); err == nil && u.Scheme == "" {
-		u.Scheme = "https"
-		strURL = u.String()
-	}
-	return strURL
-}
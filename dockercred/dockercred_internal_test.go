@@ -0,0 +1,75 @@
+package dockercred
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/xakep666/gkpxc"
+)
+
+func TestChooseEntry(t *testing.T) {
+	entries := []gkpxc.LoginEntry{
+		{UUID: "1", Login: "user1"},
+		{UUID: "2", Login: "user2"},
+		{UUID: "3", Login: "user2"},
+	}
+
+	cases := []struct {
+		name      string
+		entries   []gkpxc.LoginEntry
+		stored    storedEntry
+		preferred string
+		expect    string
+		err       error
+	}{
+		{name: "by uuid", entries: entries, stored: storedEntry{UUID: "3", Username: "user2"}, expect: "3"},
+		{name: "by stored username", entries: entries, stored: storedEntry{Username: "user1"}, expect: "1"},
+		{name: "by preferred username", entries: entries, preferred: "user1", expect: "1"},
+		{name: "stale uuid", entries: entries, stored: storedEntry{UUID: "4", Username: "user1"}, expect: "1"},
+		{name: "single", entries: entries[:1], expect: "1"},
+		{name: "ambiguous username", entries: entries, stored: storedEntry{Username: "user2"}, err: ErrAmbiguousCredentials},
+		{name: "ambiguous", entries: entries, err: ErrAmbiguousCredentials},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			entry, err := chooseEntry(c.entries, c.stored, c.preferred)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("Got error %v, expected %v", err, c.err)
+			}
+
+			if entry.UUID != c.expect {
+				t.Fatalf("Got entry %+v, expected uuid %q", entry, c.expect)
+			}
+		})
+	}
+}
+
+func TestEntryIndex(t *testing.T) {
+	path := t.TempDir() + "/state/entries.json"
+
+	idx, err := loadEntryIndex(path)
+	if err != nil {
+		t.Fatalf("Load: unexpected error %s", err)
+	}
+
+	if err = idx.set("https://registry.com", storedEntry{UUID: "1", Username: "user"}); err != nil {
+		t.Fatalf("Set: unexpected error %s", err)
+	}
+
+	if idx, err = loadEntryIndex(path); err != nil {
+		t.Fatalf("Reload: unexpected error %s", err)
+	}
+
+	if stored := idx.get("https://registry.com"); stored.UUID != "1" || stored.Username != "user" {
+		t.Fatalf("Unexpected stored entry %+v", stored)
+	}
+
+	if err = idx.remove("https://registry.com"); err != nil {
+		t.Fatalf("Remove: unexpected error %s", err)
+	}
+
+	if stored := idx.get("https://registry.com"); stored != (storedEntry{}) {
+		t.Fatalf("Unexpected stored entry after remove %+v", stored)
+	}
+}
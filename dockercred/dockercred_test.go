@@ -0,0 +1,325 @@
+package dockercred_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/99designs/keyring"
+	"github.com/docker/docker-credential-helpers/credentials"
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/dockercred"
+	"github.com/xakep666/gkpxc/gkpxctest"
+)
+
+func newHelper(t *testing.T, srv *gkpxctest.Server, kr keyring.Keyring, cfg dockercred.Config) *dockercred.KeepassXCHelper {
+	if cfg.StateFile == "" {
+		cfg.StateFile = filepath.Join(t.TempDir(), "entries.json")
+	}
+
+	h := &dockercred.KeepassXCHelper{
+		Keyring: kr,
+		Config:  cfg,
+		NewClient: func(ctx context.Context, opts ...gkpxc.ClientOption) (*gkpxc.Client, error) {
+			return gkpxc.NewClient(ctx, append(opts, gkpxc.WithConn(srv.Dial()))...)
+		},
+	}
+
+	t.Cleanup(func() { h.Close() })
+
+	return h
+}
+
+// associatedKeyring returns keyring with association credentials known by server.
+func associatedKeyring(t *testing.T, srv *gkpxctest.Server) keyring.Keyring {
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("Generate key", err)
+	}
+
+	cred := gkpxc.AssociationCredentials{ID: "test", Hash: srv.DatabaseHash(), PublicKey: *pub, PrivateKey: *priv}
+	srv.AddAssociation(cred)
+
+	serialized, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal("Serialize credentials", err)
+	}
+
+	return keyring.NewArrayKeyring([]keyring.Item{{Key: srv.DatabaseHash(), Data: serialized}})
+}
+
+func TestKeepassXCHelper(t *testing.T) {
+	srv := gkpxctest.NewTestServer(t)
+	kr := keyring.NewArrayKeyring(nil)
+	h := newHelper(t, srv, kr, dockercred.Config{})
+
+	t.Run("association bootstrap", func(t *testing.T) {
+		_, _, err := h.Get("registry.com")
+		if !credentials.IsErrCredentialsNotFound(err) {
+			t.Fatalf("Unexpected error %v, expected credentials not found", err)
+		}
+
+		if srv.Associations() != 1 {
+			t.Fatalf("Expected one association, got %d", srv.Associations())
+		}
+
+		if _, err = kr.Get(srv.DatabaseHash()); err != nil {
+			t.Fatalf("Association credentials not stored: %s", err)
+		}
+	})
+
+	t.Run("add and get", func(t *testing.T) {
+		err := h.Add(&credentials.Credentials{ServerURL: "registry.com", Username: "user", Secret: "pass"})
+		if err != nil {
+			t.Fatal("Add", err)
+		}
+
+		user, pass, err := h.Get("https://registry.com")
+		if err != nil {
+			t.Fatal("Get", err)
+		}
+
+		if user != "user" || pass != "pass" {
+			t.Fatalf("Expected user:pass, got %s:%s", user, pass)
+		}
+
+		groups := srv.Groups()
+		if len(groups.Children) != 1 || groups.Children[0].Name != credentials.CredsLabel {
+			t.Fatalf("Unexpected groups %+v", groups)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		err := h.Add(&credentials.Credentials{ServerURL: "registry.com", Username: "user", Secret: "newpass"})
+		if err != nil {
+			t.Fatal("Add", err)
+		}
+
+		if entries := srv.Entries(); len(entries) != 1 || entries[0].Password != "newpass" {
+			t.Fatalf("Unexpected entries %+v", entries)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		list, err := h.List()
+		if err != nil || len(list) != 0 {
+			t.Fatalf("Unexpected list result %v, %v", list, err)
+		}
+	})
+
+	t.Run("identity token kept apart", func(t *testing.T) {
+		err := h.Add(&credentials.Credentials{ServerURL: "registry.com", Username: "<token>", Secret: "identity"})
+		if err != nil {
+			t.Fatal("Add", err)
+		}
+
+		user, secret, err := h.Get("registry.com")
+		if err != nil {
+			t.Fatal("Get", err)
+		}
+
+		if user != "<token>" || secret != "identity" {
+			t.Fatalf("Expected <token>:identity, got %s:%s", user, secret)
+		}
+
+		if len(srv.Entries()) != 2 {
+			t.Fatalf("Password entry must be kept, got %+v", srv.Entries())
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := h.Delete("registry.com"); err != nil {
+			t.Fatal("Delete", err)
+		}
+
+		// only last stored entry deleted
+		if entries := srv.Entries(); len(entries) != 1 || entries[0].Login != "user" {
+			t.Fatalf("Unexpected entries %+v", entries)
+		}
+	})
+
+	t.Run("delete not found", func(t *testing.T) {
+		err := h.Delete("other.registry.com")
+		if !credentials.IsErrCredentialsNotFound(err) {
+			t.Fatalf("Unexpected error %v, expected credentials not found", err)
+		}
+	})
+}
+
+func TestKeepassXCHelper_Existing_association(t *testing.T) {
+	srv := gkpxctest.NewTestServer(t)
+	srv.AddEntry(gkpxctest.Entry{URL: "https://registry.com", Login: "user1", Password: "pass1"})
+	srv.AddEntry(gkpxctest.Entry{URL: "https://registry.com", Login: "user2", Password: "pass2"})
+
+	h := newHelper(t, srv, associatedKeyring(t, srv), dockercred.Config{Username: "user2"})
+
+	user, pass, err := h.Get("registry.com")
+	if err != nil {
+		t.Fatal("Get", err)
+	}
+
+	if user != "user2" || pass != "pass2" {
+		t.Fatalf("Expected user2:pass2, got %s:%s", user, pass)
+	}
+
+	if srv.Associations() != 1 {
+		t.Fatalf("New association must not be created, got %d associations", srv.Associations())
+	}
+}
+
+func TestKeepassXCHelper_Ambiguous(t *testing.T) {
+	srv := gkpxctest.NewTestServer(t)
+	srv.AddEntry(gkpxctest.Entry{URL: "https://registry.com", Login: "user1", Password: "pass1"})
+	srv.AddEntry(gkpxctest.Entry{URL: "https://registry.com", Login: "user2", Password: "pass2"})
+
+	h := newHelper(t, srv, associatedKeyring(t, srv), dockercred.Config{})
+
+	if _, _, err := h.Get("registry.com"); !errors.Is(err, dockercred.ErrAmbiguousCredentials) {
+		t.Fatalf("Unexpected error %v, expected ErrAmbiguousCredentials", err)
+	}
+
+	if err := h.Delete("registry.com"); !errors.Is(err, dockercred.ErrAmbiguousCredentials) {
+		t.Fatalf("Unexpected error %v, expected ErrAmbiguousCredentials", err)
+	}
+
+	if len(srv.Entries()) != 2 {
+		t.Fatalf("Entries must not be deleted, got %+v", srv.Entries())
+	}
+}
+
+func TestKeepassXCHelper_Locked(t *testing.T) {
+	t.Run("non-interactive", func(t *testing.T) {
+		srv := gkpxctest.NewTestServer(t)
+		srv.SetLocked(true, true)
+
+		h := newHelper(t, srv, associatedKeyring(t, srv), dockercred.Config{NonInteractive: true})
+
+		if _, _, err := h.Get("registry.com"); !errors.Is(err, dockercred.ErrLocked) {
+			t.Fatalf("Unexpected error %v, expected ErrLocked", err)
+		}
+	})
+
+	t.Run("unlock requested", func(t *testing.T) {
+		srv := gkpxctest.NewTestServer(t)
+		srv.AddEntry(gkpxctest.Entry{URL: "https://registry.com", Login: "user", Password: "pass"})
+		srv.SetLocked(true, true)
+
+		h := newHelper(t, srv, associatedKeyring(t, srv), dockercred.Config{})
+
+		if _, _, err := h.Get("registry.com"); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	})
+
+	t.Run("not unlocked", func(t *testing.T) {
+		srv := gkpxctest.NewTestServer(t)
+		srv.SetLocked(true, false)
+
+		h := newHelper(t, srv, associatedKeyring(t, srv), dockercred.Config{})
+
+		if _, _, err := h.Get("registry.com"); !errors.Is(err, dockercred.ErrLocked) {
+			t.Fatalf("Unexpected error %v, expected ErrLocked", err)
+		}
+	})
+}
+
+func TestKeepassXCHelper_Denied(t *testing.T) {
+	t.Run("association", func(t *testing.T) {
+		srv := gkpxctest.NewTestServer(t)
+		srv.SetApprover(func(string) bool { return false })
+
+		h := newHelper(t, srv, keyring.NewArrayKeyring(nil), dockercred.Config{})
+
+		if _, _, err := h.Get("registry.com"); !errors.Is(err, dockercred.ErrAssociationDenied) {
+			t.Fatalf("Unexpected error %v, expected ErrAssociationDenied", err)
+		}
+	})
+
+	t.Run("association in non-interactive mode", func(t *testing.T) {
+		srv := gkpxctest.NewTestServer(t)
+
+		h := newHelper(t, srv, keyring.NewArrayKeyring(nil), dockercred.Config{NonInteractive: true})
+
+		if _, _, err := h.Get("registry.com"); !errors.Is(err, dockercred.ErrAssociationRequired) {
+			t.Fatalf("Unexpected error %v, expected ErrAssociationRequired", err)
+		}
+
+		if srv.Associations() != 0 {
+			t.Fatalf("Association must not be requested")
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		srv := gkpxctest.NewTestServer(t)
+		srv.AddEntry(gkpxctest.Entry{URL: "https://registry.com", Login: "user", Password: "pass"})
+		srv.SetApprover(func(action string) bool { return action != "delete-entry" })
+
+		h := newHelper(t, srv, associatedKeyring(t, srv), dockercred.Config{})
+
+		if err := h.Delete("registry.com"); !errors.Is(err, dockercred.ErrDeleteDenied) {
+			t.Fatalf("Unexpected error %v, expected ErrDeleteDenied", err)
+		}
+
+		if len(srv.Entries()) != 1 {
+			t.Fatalf("Entry must not be deleted")
+		}
+	})
+}
+
+func TestKeepassXCHelper_Not_running(t *testing.T) {
+	h := &dockercred.KeepassXCHelper{
+		Keyring: keyring.NewArrayKeyring(nil),
+		NewClient: func(ctx context.Context, opts ...gkpxc.ClientOption) (*gkpxc.Client, error) {
+			return nil, gkpxc.ErrConnectFailed
+		},
+	}
+
+	if _, _, err := h.Get("registry.com"); !errors.Is(err, dockercred.ErrNotRunning) {
+		t.Fatalf("Unexpected error %v, expected ErrNotRunning", err)
+	}
+}
+
+func TestKeepassXCHelper_Revoked_association(t *testing.T) {
+	t.Run("reassociate", func(t *testing.T) {
+		srv := gkpxctest.NewTestServer(t)
+		kr := associatedKeyring(t, srv)
+		srv.RemoveAssociation("test")
+
+		h := newHelper(t, srv, kr, dockercred.Config{})
+
+		if _, _, err := h.Get("registry.com"); !credentials.IsErrCredentialsNotFound(err) {
+			t.Fatalf("Unexpected error %v, expected credentials not found", err)
+		}
+
+		item, err := kr.Get(srv.DatabaseHash())
+		if err != nil {
+			t.Fatal("New association not stored", err)
+		}
+
+		var cred gkpxc.AssociationCredentials
+		if err = json.Unmarshal(item.Data, &cred); err != nil || cred.ID == "test" {
+			t.Fatalf("Unexpected stored association %+v, %v", cred, err)
+		}
+	})
+
+	t.Run("reassociation disabled", func(t *testing.T) {
+		srv := gkpxctest.NewTestServer(t)
+		kr := associatedKeyring(t, srv)
+		srv.RemoveAssociation("test")
+
+		h := newHelper(t, srv, kr, dockercred.Config{NoReassociation: true})
+
+		if _, _, err := h.Get("registry.com"); !errors.Is(err, dockercred.ErrAssociationRevoked) {
+			t.Fatalf("Unexpected error %v, expected ErrAssociationRevoked", err)
+		}
+
+		if _, err := kr.Get(srv.DatabaseHash()); !errors.Is(err, keyring.ErrKeyNotFound) {
+			t.Fatalf("Revoked association must be removed, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package dockercred
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/internal/bootstrap"
+)
+
+// Errors returned by KeepassXCHelper in addition to credentials package ones.
+var (
+	// ErrNotRunning returned if KeepassXC is not running or browser integration is disabled.
+	ErrNotRunning = errors.New("KeepassXC is not running or browser integration is disabled")
+
+	// ErrInsecureSocket returned if KeepassXC socket or process listening it is owned by another user.
+	// Secrets are not sent to such socket because it may be spoofed.
+	ErrInsecureSocket = errors.New("KeepassXC socket is insecure, it may be spoofed by another user")
+
+	// ErrLocked returned if KeepassXC database is locked.
+	ErrLocked = errors.New("KeepassXC database is locked")
+
+	// ErrAssociationDenied returned if user denied association request in KeepassXC.
+	ErrAssociationDenied = errors.New("KeepassXC association denied")
+
+	// ErrAssociationRequired returned in non-interactive mode if helper is not associated with database yet.
+	ErrAssociationRequired = errors.New("KeepassXC association required, run helper in interactive mode first")
+
+	// ErrAssociationRevoked returned if association was removed in KeepassXC and re-association is disabled.
+	ErrAssociationRevoked = errors.New("KeepassXC association was removed and re-association is disabled")
+
+	// ErrTimeout returned if KeepassXC didn't respond in time, i.e. confirmation dialog was not answered.
+	ErrTimeout = errors.New("KeepassXC response timed out, confirmation may be pending")
+)
+
+// classifyError replaces low-level errors with descriptive ones.
+func classifyError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case credentialsNotFound(err):
+		return err
+	case errors.Is(err, gkpxc.ErrInsecureSocket):
+		return fmt.Errorf("%w: %s", ErrInsecureSocket, err)
+	case errors.Is(err, gkpxc.ErrConnectFailed):
+		return fmt.Errorf("%w: %s", ErrNotRunning, err)
+	case gkpxc.IsErrorCode(err, gkpxc.ErrCodeDatabaseNotOpened):
+		return fmt.Errorf("%w: %s", ErrLocked, err)
+	case errors.Is(err, bootstrap.ErrAssociationDenied):
+		return fmt.Errorf("%w: %s", ErrAssociationDenied, err)
+	case errors.Is(err, bootstrap.ErrAssociationRequired):
+		return ErrAssociationRequired
+	case errors.Is(err, bootstrap.ErrAssociationRevoked):
+		return ErrAssociationRevoked
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %s", ErrTimeout, err)
+	default:
+		return err
+	}
+}
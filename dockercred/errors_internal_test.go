@@ -0,0 +1,57 @@
+package dockercred
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/docker/docker-credential-helpers/credentials"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/internal/bootstrap"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err    error
+		expect error
+	}{
+		{err: fmt.Errorf("keepassxc connect failed: %w", gkpxc.ErrConnectFailed), expect: ErrNotRunning},
+		{err: fmt.Errorf("%w: socket has mode 0777", gkpxc.ErrInsecureSocket), expect: ErrInsecureSocket},
+		{err: &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeDatabaseNotOpened}, expect: ErrLocked},
+		{err: fmt.Errorf("%w: test", bootstrap.ErrAssociationDenied), expect: ErrAssociationDenied},
+		{err: bootstrap.ErrAssociationRequired, expect: ErrAssociationRequired},
+		{err: bootstrap.ErrAssociationRevoked, expect: ErrAssociationRevoked},
+		{err: fmt.Errorf("get logins: %w", context.DeadlineExceeded), expect: ErrTimeout},
+	}
+
+	for _, c := range cases {
+		if err := classifyError(c.err); !errors.Is(err, c.expect) {
+			t.Fatalf("Error %q classified as %q, expected %q", c.err, err, c.expect)
+		}
+	}
+
+	if err := classifyError(credentials.NewErrCredentialsNotFound()); !credentials.IsErrCredentialsNotFound(err) {
+		t.Fatalf("Credentials not found error must be kept, got %q", err)
+	}
+
+	if err := classifyError(nil); err != nil {
+		t.Fatalf("Unexpected error %q", err)
+	}
+}
+
+func TestConfig_timeout(t *testing.T) {
+	if timeout := (Config{}).timeout(); timeout != 0 {
+		t.Fatalf("Unexpected default timeout %s", timeout)
+	}
+
+	if timeout := (Config{NonInteractive: true}).timeout(); timeout != defaultNonInteractiveTimeout {
+		t.Fatalf("Unexpected non-interactive timeout %s", timeout)
+	}
+
+	if timeout := (Config{NonInteractive: true, Timeout: Duration(time.Minute)}).timeout(); timeout != time.Minute {
+		t.Fatalf("Unexpected configured timeout %s", timeout)
+	}
+}
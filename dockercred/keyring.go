@@ -1,50 +1,33 @@
 package dockercred
 
 import (
+	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
 
 	"github.com/99designs/keyring"
+
+	"github.com/xakep666/gkpxc/internal/bootstrap"
 )
 
+// SetupKeyring opens OS keyring. File backend is allowed if EnvAskPass is set.
 func SetupKeyring(service string) (keyring.Keyring, error) {
-	backends := []keyring.BackendType{
-		// Windows
-		keyring.WinCredBackend,
-		// MacOS
-		keyring.KeychainBackend,
-		// Linux
-		keyring.KWalletBackend,
-		keyring.SecretServiceBackend,
-	}
-
-	promptCmd := os.Getenv("DOCKER_CREDENTIAL_KEEPASSXC_ASKPASS")
-	if promptCmd != "" {
-		backends = append(backends, keyring.FileBackend)
-	}
-
-	return keyring.Open(keyring.Config{
-		AllowedBackends:          backends,
-		WinCredPrefix:            service,
-		KeychainName:             "login",
-		KeychainTrustApplication: true,
-		KWalletAppID:             service,
-		KWalletFolder:            service,
-		LibSecretCollectionName:  service,
-		FileDir:                  fileBackendDir(service),
-		FilePasswordFunc: func(prompt string) (string, error) {
-			out, err := exec.Command(promptCmd, prompt).Output()
-			return string(out), err
-		},
-	})
+	return bootstrap.SetupKeyring(service, os.Getenv(EnvAskPass))
 }
 
-func fileBackendDir(service string) string {
-	cfgDir, err := os.UserConfigDir()
-	if err != nil {
-		return ""
+// OpenStore opens association credentials store configured in cfg.
+// File store passphrase is prompted with command from EnvAskPass if neither key file nor credential set.
+func OpenStore(service string, cfg Config) (keyring.Keyring, error) {
+	switch storeType := cfg.Store.storeType(); storeType {
+	case StoreKeyring:
+		return SetupKeyring(service)
+	case StoreFile:
+		return bootstrap.OpenFileStore(service, bootstrap.FileStoreOptions{
+			Path:       cfg.Store.Path,
+			Credential: cfg.Store.Credential,
+			KeyFile:    cfg.Store.KeyFile,
+			AskPassCmd: os.Getenv(EnvAskPass),
+		})
+	default:
+		return nil, fmt.Errorf("unknown store type %q", storeType)
 	}
-
-	return filepath.Join(cfgDir, service)
 }
@@ -0,0 +1,82 @@
+package dockercred
+
+import (
+	"net/url"
+	"strings"
+)
+
+const dockerHubURL = "https://index.docker.io/v1/"
+
+var dockerHubHosts = []string{"index.docker.io", "docker.io", "registry-1.docker.io"}
+
+// normalizeURL adds scheme to registry URL if it's missing.
+func normalizeURL(serverURL, scheme string) *url.URL {
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		// url like "registry.com:5000/path" parsed wrong without scheme
+		if u, err = url.Parse(scheme + "://" + serverURL); err != nil {
+			return &url.URL{Scheme: scheme, Host: serverURL}
+		}
+	}
+
+	return u
+}
+
+// lookupURLs returns URLs to lookup credentials for registry in order of preference.
+func (c Config) lookupURLs(serverURL string) []string {
+	var (
+		ret  []string
+		seen = make(map[string]bool)
+	)
+
+	add := func(u url.URL) {
+		s := u.String()
+		if !seen[s] {
+			seen[s] = true
+			ret = append(ret, s)
+		}
+	}
+
+	schemes := c.schemes()
+	base := normalizeURL(serverURL, schemes[0])
+
+	hosts := []string{base.Host}
+	if !c.Lookup.NoDockerHubAliases && isDockerHub(base.Host) {
+		add(*normalizeURL(dockerHubURL, schemes[0]))
+		hosts = append(hosts, dockerHubHosts...)
+	}
+
+	// scheme from original url goes first
+	schemes = append([]string{base.Scheme}, schemes...)
+
+	for _, host := range hosts {
+		for _, scheme := range schemes {
+			u := url.URL{Scheme: scheme, Host: host, Path: base.Path}
+			add(u)
+
+			if !c.Lookup.V2Suffix {
+				continue
+			}
+
+			if trimmed := strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/v2"); trimmed != strings.TrimSuffix(u.Path, "/") {
+				u.Path = trimmed
+			} else {
+				u.Path = trimmed + "/v2/"
+			}
+
+			add(u)
+		}
+	}
+
+	return ret
+}
+
+func isDockerHub(host string) bool {
+	for _, h := range dockerHubHosts {
+		if host == h {
+			return true
+		}
+	}
+
+	return false
+}
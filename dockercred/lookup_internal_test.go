@@ -0,0 +1,97 @@
+package dockercred
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfig_lookupURLs(t *testing.T) {
+	cases := []struct {
+		name      string
+		cfg       Config
+		serverURL string
+		expect    []string
+	}{
+		{
+			name:      "default",
+			serverURL: "registry.com:5000",
+			expect:    []string{"https://registry.com:5000"},
+		},
+		{
+			name:      "explicit scheme",
+			cfg:       Config{Lookup: LookupConfig{Schemes: []string{"https", "http"}}},
+			serverURL: "http://registry.com",
+			expect:    []string{"http://registry.com", "https://registry.com"},
+		},
+		{
+			name:      "v2 suffix",
+			cfg:       Config{Lookup: LookupConfig{V2Suffix: true}},
+			serverURL: "https://registry.com/v2/",
+			expect:    []string{"https://registry.com/v2/", "https://registry.com"},
+		},
+		{
+			name:      "docker hub",
+			serverURL: "https://index.docker.io/v1/",
+			expect: []string{
+				"https://index.docker.io/v1/",
+				"https://docker.io/v1/",
+				"https://registry-1.docker.io/v1/",
+			},
+		},
+		{
+			name:      "docker hub alias",
+			serverURL: "docker.io",
+			expect: []string{
+				"https://index.docker.io/v1/",
+				"https://docker.io",
+				"https://index.docker.io",
+				"https://registry-1.docker.io",
+			},
+		},
+		{
+			name:      "docker hub without aliases",
+			cfg:       Config{Lookup: LookupConfig{NoDockerHubAliases: true}},
+			serverURL: "docker.io",
+			expect:    []string{"https://docker.io"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if urls := c.cfg.lookupURLs(c.serverURL); !reflect.DeepEqual(urls, c.expect) {
+				t.Fatalf("Got %v, expected %v", urls, c.expect)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv(EnvConfig, "testdata/config.json")
+	t.Setenv(EnvSchemes, "http,https")
+	t.Setenv(EnvStoreCred, "docker-store-key")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	expect := Config{
+		Group:     "Infra/Docker",
+		Lookup:    LookupConfig{Schemes: []string{"http", "https"}, V2Suffix: true},
+		Usernames: map[string]string{"registry.com": "ci"},
+		StateFile: "/tmp/entries.json",
+		Store:     StoreConfig{Path: "/tmp/associations.json", Credential: "docker-store-key"},
+	}
+
+	if !reflect.DeepEqual(cfg, expect) {
+		t.Fatalf("Got %+v, expected %+v", cfg, expect)
+	}
+
+	if cfg.preferredUsername("registry.com") != "ci" || cfg.preferredUsername("other.com") != "" {
+		t.Fatalf("Unexpected preferred usernames")
+	}
+
+	if cfg.Store.storeType() != StoreFile || (StoreConfig{}).storeType() != StoreKeyring {
+		t.Fatalf("Unexpected store type")
+	}
+}
@@ -0,0 +1,92 @@
+package dockercred
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const stateFile = "entries.json"
+
+// storedEntry remembers which entry was stored for registry by Add.
+type storedEntry struct {
+	UUID     string `json:"uuid,omitempty"`
+	Username string `json:"username"`
+
+	// Expires is set for identity tokens with known expiration time.
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// entryIndex maps normalized registry URL to entry stored for it. It's persisted if path is not empty.
+type entryIndex struct {
+	path    string
+	entries map[string]storedEntry
+}
+
+// StatePath returns default path of file used to remember stored entries.
+func StatePath() (string, error) {
+	cfgDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cfgDir, configFolder, stateFile), nil
+}
+
+func loadEntryIndex(path string) (*entryIndex, error) {
+	idx := &entryIndex{path: path, entries: make(map[string]storedEntry)}
+	if path == "" {
+		return idx, nil
+	}
+
+	content, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, nil):
+		if err = json.Unmarshal(content, &idx.entries); err != nil {
+			return nil, fmt.Errorf("parse state %s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+	default:
+		return nil, fmt.Errorf("read state: %w", err)
+	}
+
+	return idx, nil
+}
+
+func (idx *entryIndex) get(serverURL string) storedEntry {
+	return idx.entries[serverURL]
+}
+
+func (idx *entryIndex) set(serverURL string, entry storedEntry) error {
+	idx.entries[serverURL] = entry
+	return idx.save()
+}
+
+func (idx *entryIndex) remove(serverURL string) error {
+	delete(idx.entries, serverURL)
+	return idx.save()
+}
+
+func (idx *entryIndex) save() error {
+	if idx.path == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(idx.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("serialize state: %w", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(idx.path), 0o700); err != nil {
+		return fmt.Errorf("create state dir: %w", err)
+	}
+
+	if err = os.WriteFile(idx.path, content, 0o600); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package dockercred
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/xakep666/gkpxc"
+)
+
+const (
+	// identityTokenUsername is used by docker as user name when registry issued identity token.
+	identityTokenUsername = "<token>"
+
+	// IdentityTokenField is an advanced string field which marks entry password as identity token.
+	// It may be set in KeepassXC manually for entries with another login.
+	IdentityTokenField = "docker-identity-token"
+)
+
+// Duration is time.Duration which may be unmarshalled from string like "1h30m".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func isIdentityToken(entry gkpxc.LoginEntry) bool {
+	if entry.Login == identityTokenUsername {
+		return true
+	}
+
+	_, ok := entry.StringField(IdentityTokenField)
+
+	return ok
+}
+
+// tokenExpiry returns identity token expiration time. It's taken from JWT "exp" claim if token is JWT,
+// otherwise ttl from now used. Zero time returned if expiry is unknown.
+func tokenExpiry(token string, ttl time.Duration, now time.Time) time.Time {
+	if exp, ok := jwtExpiry(token); ok {
+		return exp
+	}
+
+	if ttl > 0 {
+		return now.Add(ttl)
+	}
+
+	return time.Time{}
+}
+
+func jwtExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		Exp *json.Number `json:"exp"`
+	}
+
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.Exp == nil {
+		return time.Time{}, false
+	}
+
+	exp, err := claims.Exp.Int64()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(exp, 0), true
+}
+
+// identityTokenExpired checks if identity token entry must not be served. Stored entry is one remembered
+// for normalized registry URL. If its UUID is unknown (created entry was not found after adding) it's matched by user name.
+func identityTokenExpired(entry gkpxc.LoginEntry, stored storedEntry, now time.Time) bool {
+	if entry.Expired {
+		return true
+	}
+
+	sameEntry := stored.UUID == entry.UUID || (stored.UUID == "" && stored.Username == entry.Login)
+	if sameEntry && !stored.Expires.IsZero() && !now.Before(stored.Expires) {
+		return true
+	}
+
+	if exp, ok := jwtExpiry(entry.Password); ok && !now.Before(exp) {
+		return true
+	}
+
+	return false
+}
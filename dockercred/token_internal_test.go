@@ -0,0 +1,82 @@
+package dockercred
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/xakep666/gkpxc"
+)
+
+func testJWT(payload string) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString([]byte(payload)) + ".sig"
+}
+
+func TestTokenExpiry(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	if exp := tokenExpiry(testJWT(`{"exp":2000}`), time.Hour, now); !exp.Equal(time.Unix(2000, 0)) {
+		t.Fatalf("Unexpected JWT expiry %s", exp)
+	}
+
+	if exp := tokenExpiry("opaque", time.Hour, now); !exp.Equal(now.Add(time.Hour)) {
+		t.Fatalf("Unexpected TTL expiry %s", exp)
+	}
+
+	if exp := tokenExpiry(testJWT(`{"sub":"user"}`), 0, now); !exp.IsZero() {
+		t.Fatalf("Unexpected expiry %s, expected zero", exp)
+	}
+}
+
+func TestIdentityToken(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	if !isIdentityToken(gkpxc.LoginEntry{Login: "<token>"}) ||
+		!isIdentityToken(gkpxc.LoginEntry{Login: "user", StringFields: []map[string]string{{"KPH: docker-identity-token": ""}}}) ||
+		isIdentityToken(gkpxc.LoginEntry{Login: "user"}) {
+		t.Fatal("Unexpected identity token detection")
+	}
+
+	cases := []struct {
+		name    string
+		entry   gkpxc.LoginEntry
+		stored  storedEntry
+		expired bool
+	}{
+		{name: "not expired", entry: gkpxc.LoginEntry{UUID: "1", Password: "opaque"}},
+		{name: "entry expired", entry: gkpxc.LoginEntry{UUID: "1", Expired: true}, expired: true},
+		{
+			name:    "stored expiry",
+			entry:   gkpxc.LoginEntry{UUID: "1", Password: "opaque"},
+			stored:  storedEntry{UUID: "1", Expires: now.Add(-time.Second)},
+			expired: true,
+		},
+		{
+			name:   "stored expiry for another entry",
+			entry:  gkpxc.LoginEntry{UUID: "1", Password: "opaque"},
+			stored: storedEntry{UUID: "2", Expires: now.Add(-time.Second)},
+		},
+		{
+			name:    "stored expiry without uuid",
+			entry:   gkpxc.LoginEntry{UUID: "1", Login: "<token>", Password: "opaque"},
+			stored:  storedEntry{Username: "<token>", Expires: now.Add(-time.Second)},
+			expired: true,
+		},
+		{
+			name:   "stored expiry without uuid for another user",
+			entry:  gkpxc.LoginEntry{UUID: "1", Login: "user", Password: "opaque"},
+			stored: storedEntry{Username: "<token>", Expires: now.Add(-time.Second)},
+		},
+		{name: "jwt expired", entry: gkpxc.LoginEntry{UUID: "1", Password: testJWT(`{"exp":999}`)}, expired: true},
+		{name: "jwt not expired", entry: gkpxc.LoginEntry{UUID: "1", Password: testJWT(`{"exp":1001}`)}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if expired := identityTokenExpired(c.entry, c.stored, now); expired != c.expired {
+				t.Fatalf("Got expired %t, expected %t", expired, c.expired)
+			}
+		})
+	}
+}
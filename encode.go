@@ -0,0 +1,94 @@
+package gkpxc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// maxPooledBuffer limits size of buffers returned to pool so huge responses don't pin memory.
+const maxPooledBuffer = 64 << 10
+
+// pooledBuffer is a buffer with encoder writing to it, they're pooled together.
+type pooledBuffer struct {
+	bytes.Buffer
+	encoder *json.Encoder
+}
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := new(pooledBuffer)
+		buf.encoder = json.NewEncoder(&buf.Buffer)
+
+		return buf
+	},
+}
+
+func getBuffer() *pooledBuffer {
+	return bufferPool.Get().(*pooledBuffer)
+}
+
+// putBuffer wipes buffer contents (they may contain secrets) and returns it to pool.
+func putBuffer(buf *pooledBuffer) {
+	wipe(buf.Bytes()[:buf.Cap()])
+	buf.Reset()
+
+	if buf.Cap() <= maxPooledBuffer {
+		bufferPool.Put(buf)
+	}
+}
+
+// encodeRequest writes request JSON object with "action" field to empty buf. Action is added to the end of object
+// so it overrides same field of request if any. Nil request gives object with action only.
+func encodeRequest(buf *pooledBuffer, action string, req interface{}) error {
+	if req != nil {
+		if err := buf.encoder.Encode(req); err != nil {
+			return err
+		}
+	}
+
+	// Encoder adds newline and json.Marshaler output is compacted, so only closing brace should be trimmed.
+	obj := bytes.TrimRight(buf.Bytes(), "\n")
+
+	switch {
+	case len(obj) == 0, string(obj) == "null":
+		buf.Reset()
+		buf.WriteByte('{')
+	case obj[0] != '{' || obj[len(obj)-1] != '}':
+		return fmt.Errorf("request must be JSON object, got %T", req)
+	case len(obj) == 2: // empty object
+		buf.Truncate(1)
+	default:
+		buf.Truncate(len(obj) - 1)
+		buf.WriteByte(',')
+	}
+
+	buf.WriteString(`"action":`)
+
+	if err := writeString(&buf.Buffer, action); err != nil {
+		return err
+	}
+
+	buf.WriteByte('}')
+
+	return nil
+}
+
+// writeString writes JSON string. Action names usually don't need escaping so they're written as is.
+func writeString(buf *bytes.Buffer, s string) error {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c >= 0x7f || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			quoted, err := json.Marshal(s)
+			buf.Write(quoted)
+
+			return err
+		}
+	}
+
+	buf.WriteByte('"')
+	buf.WriteString(s)
+	buf.WriteByte('"')
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package gkpxc
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEncodeRequest(t *testing.T) {
+	cases := []struct {
+		name   string
+		action string
+		req    interface{}
+		expect string
+	}{
+		{name: "struct", action: "get-totp", req: GetTOTPRequest{UUID: "1"}, expect: `{"uuid":"1","action":"get-totp"}`},
+		{name: "empty", action: "get-databasehash", req: GetDatabaseHashRequest{}, expect: `{"action":"get-databasehash"}`},
+		{name: "nil", action: "lock-database", expect: `{"action":"lock-database"}`},
+		{name: "nil pointer", action: "lock-database", req: (*GetTOTPRequest)(nil), expect: `{"action":"lock-database"}`},
+		{name: "override", action: "a", req: map[string]string{"action": "b"}, expect: `{"action":"b","action":"a"}`},
+		{name: "raw", action: "x", req: json.RawMessage(` { "k" : 1 } `), expect: `{"k":1,"action":"x"}`},
+		{name: "escaping", action: `"<x>"`, expect: `{"action":"\"\u003cx\u003e\""}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			buf := getBuffer()
+			defer putBuffer(buf)
+
+			if err := encodeRequest(buf, c.action, c.req); err != nil {
+				t.Fatal("encodeRequest", err)
+			}
+
+			if buf.String() != c.expect {
+				t.Fatalf("Got %s, expected %s", buf, c.expect)
+			}
+
+			var decoded map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded["action"] != c.action {
+				t.Fatalf("Unexpected decoded request %v: %v", decoded, err)
+			}
+		})
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if err := encodeRequest(buf, "x", []string{"a"}); err == nil {
+		t.Fatalf("Expected error for non-object request")
+	}
+}
+
+func BenchmarkEncodeRequest(b *testing.B) {
+	req := SetLoginRequest{URL: "https://example.com", Login: "user", Password: "password", Group: "group", GroupUUID: "uuid"}
+
+	b.Run("roundtrip", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			// previous implementation
+			var reqMap map[string]interface{}
+
+			msg, _ := json.Marshal(req)
+			_ = json.Unmarshal(msg, &reqMap)
+			reqMap["action"] = req.Action()
+			_, _ = json.Marshal(reqMap)
+		}
+	})
+
+	b.Run("splice", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			buf := getBuffer()
+			_ = encodeRequest(buf, req.Action(), req)
+			putBuffer(buf)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package envrun
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Variable is an environment variable. Value may be a reference to KeepassXC entry.
+type Variable struct {
+	Name, Value string
+}
+
+// ParseVariable parses variable from "NAME=VALUE" form.
+func ParseVariable(s string) (Variable, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return Variable{}, fmt.Errorf("variable %q must be in NAME=VALUE form", s)
+	}
+
+	return Variable{Name: name, Value: value}, nil
+}
+
+// ParseDotEnv reads variables from dotenv-style input. Supported syntax:
+//	# comment
+//	NAME=value
+//	export NAME=value
+//	NAME="double quoted value with \"escapes\""
+//	NAME='single quoted value'
+func ParseDotEnv(r io.Reader) ([]Variable, error) {
+	var (
+		vars []Variable
+		line int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		text = strings.TrimPrefix(text, "export ")
+
+		v, err := ParseVariable(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		v.Name = strings.TrimSpace(v.Name)
+		if v.Value, err = unquote(strings.TrimSpace(v.Value)); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		vars = append(vars, v)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
+func unquote(value string) (string, error) {
+	if len(value) < 2 {
+		return value, nil
+	}
+
+	switch {
+	case value[0] == '"' && value[len(value)-1] == '"':
+		return strconv.Unquote(value)
+	case value[0] == '\'' && value[len(value)-1] == '\'':
+		return value[1 : len(value)-1], nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,96 @@
+package envrun
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	cases := []struct {
+		in     string
+		expect Reference
+	}{
+		{
+			in:     "keepassxc://db.internal#password",
+			expect: Reference{URL: "https://db.internal", Field: FieldPassword},
+		},
+		{
+			in:     "keepassxc://admin@db.internal:5432/path",
+			expect: Reference{URL: "https://db.internal:5432/path", Login: "admin", Field: FieldPassword},
+		},
+		{
+			in:     "keepassxc://site.com#username",
+			expect: Reference{URL: "https://site.com", Field: FieldLogin},
+		},
+		{
+			in:     "keepassxc://site.com#totp",
+			expect: Reference{URL: "https://site.com", Field: FieldTOTP},
+		},
+	}
+
+	for _, c := range cases {
+		ref, err := ParseReference(c.in)
+		if err != nil {
+			t.Fatalf("Parse %s: unexpected error %s", c.in, err)
+		}
+
+		if ref != c.expect {
+			t.Fatalf("Parse %s: got %+v, expected %+v", c.in, ref, c.expect)
+		}
+	}
+
+	for _, in := range []string{"https://site.com", "keepassxc:///path", "keepassxc://site.com#notes"} {
+		if _, err := ParseReference(in); err == nil {
+			t.Fatalf("Parse %s: expected error", in)
+		}
+	}
+}
+
+func TestParseDotEnv(t *testing.T) {
+	vars, err := ParseDotEnv(strings.NewReader(`
+# comment
+PLAIN=value
+export DB_PASSWORD=keepassxc://db.internal#password
+QUOTED="line\nbreak"
+SINGLE='keep \n as is'
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	expect := []Variable{
+		{Name: "PLAIN", Value: "value"},
+		{Name: "DB_PASSWORD", Value: "keepassxc://db.internal#password"},
+		{Name: "QUOTED", Value: "line\nbreak"},
+		{Name: "SINGLE", Value: `keep \n as is`},
+	}
+
+	if !reflect.DeepEqual(vars, expect) {
+		t.Fatalf("Got %+v, expected %+v", vars, expect)
+	}
+
+	if _, err = ParseDotEnv(strings.NewReader("NOVALUE")); err == nil {
+		t.Fatal("Expected error for line without value")
+	}
+}
+
+func TestMaskWriter(t *testing.T) {
+	var out bytes.Buffer
+
+	mw := newMaskWriter(&out, []string{"secret", "sec", ""})
+	for _, chunk := range []string{"my se", "cret is here, sec", "ond sec"} {
+		if _, err := mw.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write: unexpected error %s", err)
+		}
+	}
+
+	if err := mw.Flush(); err != nil {
+		t.Fatalf("Flush: unexpected error %s", err)
+	}
+
+	if expect := "my ***** is here, *****ond *****"; out.String() != expect {
+		t.Fatalf("Got %q, expected %q", out.String(), expect)
+	}
+}
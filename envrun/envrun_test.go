@@ -0,0 +1,165 @@
+package envrun_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/xakep666/gkpxc/envrun"
+	"github.com/xakep666/gkpxc/gkpxctest"
+)
+
+// childEnv switches test binary to child process mode for Runner tests.
+const childEnv = "ENVRUN_TEST_CHILD"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(childEnv) {
+	case "":
+		os.Exit(m.Run())
+	case "print":
+		fmt.Print("secret is ", os.Getenv("SECRET"))
+		os.Exit(0)
+	case "exit":
+		os.Exit(3)
+	case "kill":
+		self, _ := os.FindProcess(os.Getpid())
+		_ = self.Kill()
+		time.Sleep(time.Minute)
+	}
+}
+
+func newResolver(t *testing.T) *envrun.Resolver {
+	srv := gkpxctest.NewTestServer(t)
+	srv.AddEntry(gkpxctest.Entry{URL: "https://db.internal", Login: "admin", Password: "admin-pass", TOTP: "123456"})
+	srv.AddEntry(gkpxctest.Entry{URL: "https://db.internal", Login: "reader", Password: "reader-pass"})
+	srv.AddEntry(gkpxctest.Entry{URL: "https://api.internal", Login: "bot", Password: "token"})
+
+	return &envrun.Resolver{Client: gkpxctest.NewAssociatedClient(t, srv)}
+}
+
+func resolve(t *testing.T, r *envrun.Resolver, reference string) (string, error) {
+	ref, err := envrun.ParseReference(reference)
+	if err != nil {
+		t.Fatal("ParseReference", err)
+	}
+
+	return r.Resolve(context.Background(), ref)
+}
+
+func TestResolver(t *testing.T) {
+	r := newResolver(t)
+
+	cases := []struct {
+		ref    string
+		expect string
+	}{
+		{ref: "keepassxc://api.internal", expect: "token"},
+		{ref: "keepassxc://api.internal#username", expect: "bot"},
+		{ref: "keepassxc://admin@db.internal#password", expect: "admin-pass"},
+		{ref: "keepassxc://reader@db.internal", expect: "reader-pass"},
+		{ref: "keepassxc://admin@db.internal#totp", expect: "123456"},
+	}
+
+	for _, c := range cases {
+		value, err := resolve(t, r, c.ref)
+		if err != nil {
+			t.Fatalf("Resolve %s: unexpected error %s", c.ref, err)
+		}
+
+		if value != c.expect {
+			t.Fatalf("Resolve %s: got %q, expected %q", c.ref, value, c.expect)
+		}
+	}
+}
+
+func TestResolver_NoEntries(t *testing.T) {
+	r := newResolver(t)
+
+	for _, ref := range []string{"keepassxc://other.internal", "keepassxc://writer@db.internal"} {
+		if _, err := resolve(t, r, ref); !errors.Is(err, envrun.ErrNoEntries) {
+			t.Fatalf("Resolve %s: unexpected error %v, expected ErrNoEntries", ref, err)
+		}
+	}
+}
+
+func TestResolver_Ambiguous(t *testing.T) {
+	r := newResolver(t)
+
+	if _, err := resolve(t, r, "keepassxc://db.internal"); !errors.Is(err, envrun.ErrAmbiguousReference) {
+		t.Fatalf("Unexpected error %v, expected ErrAmbiguousReference", err)
+	}
+}
+
+func TestRunner(t *testing.T) {
+	r := newResolver(t)
+
+	var stdout bytes.Buffer
+
+	runner := envrun.Runner{Resolver: r, Stdout: &stdout}
+	vars := []envrun.Variable{{Name: childEnv, Value: "print"}, {Name: "SECRET", Value: "keepassxc://api.internal"}}
+
+	if err := runner.Run(context.Background(), vars, os.Args[0]); err != nil {
+		t.Fatal("Run", err)
+	}
+
+	if stdout.String() != "secret is token" {
+		t.Fatalf("Unexpected output %q", stdout.String())
+	}
+
+	stdout.Reset()
+	runner.Mask = true
+
+	if err := runner.Run(context.Background(), vars, os.Args[0]); err != nil {
+		t.Fatal("Run", err)
+	}
+
+	if stdout.String() != "secret is "+envrun.Mask {
+		t.Fatalf("Unexpected masked output %q", stdout.String())
+	}
+}
+
+func TestRunner_ResolveFailed(t *testing.T) {
+	r := newResolver(t)
+
+	runner := envrun.Runner{Resolver: r}
+	vars := []envrun.Variable{{Name: childEnv, Value: "print"}, {Name: "SECRET", Value: "keepassxc://db.internal"}}
+
+	if err := runner.Run(context.Background(), vars, os.Args[0]); !errors.Is(err, envrun.ErrAmbiguousReference) {
+		t.Fatalf("Unexpected error %v, expected ErrAmbiguousReference", err)
+	}
+}
+
+func TestRunner_ExitCode(t *testing.T) {
+	r := newResolver(t)
+
+	cases := []struct {
+		mode   string
+		expect int
+	}{
+		{mode: "exit", expect: 3},
+		{mode: "kill", expect: 128 + 9},
+	}
+
+	for _, c := range cases {
+		if c.mode == "kill" && runtime.GOOS == "windows" {
+			continue // no signals, killed process exits with code 1
+		}
+
+		runner := envrun.Runner{Resolver: r}
+
+		var exitErr *exec.ExitError
+		if err := runner.Run(context.Background(), []envrun.Variable{{Name: childEnv, Value: c.mode}}, os.Args[0]); !errors.As(err, &exitErr) {
+			t.Fatalf("%s: unexpected error %v, expected *exec.ExitError", c.mode, err)
+		}
+
+		if code := envrun.ExitCode(exitErr); code != c.expect {
+			t.Fatalf("%s: unexpected exit code %d, expected %d", c.mode, code, c.expect)
+		}
+	}
+}
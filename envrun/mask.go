@@ -0,0 +1,81 @@
+package envrun
+
+import (
+	"bytes"
+	"io"
+	"sort"
+)
+
+// Mask replaces secret values in output.
+const Mask = "*****"
+
+// maskWriter replaces secrets with Mask. It holds possibly incomplete secret at the end of written data
+// until the next write or flush.
+type maskWriter struct {
+	w       io.Writer
+	secrets [][]byte
+	buf     []byte
+}
+
+func newMaskWriter(w io.Writer, secrets []string) *maskWriter {
+	mw := &maskWriter{w: w}
+	for _, s := range secrets {
+		if s != "" {
+			mw.secrets = append(mw.secrets, []byte(s))
+		}
+	}
+
+	// longest secrets first to not leave parts of them unmasked
+	sort.Slice(mw.secrets, func(i, j int) bool { return len(mw.secrets[i]) > len(mw.secrets[j]) })
+
+	return mw
+}
+
+func (m *maskWriter) Write(p []byte) (int, error) {
+	m.buf = append(m.buf, p...)
+
+	if err := m.mask(false); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Flush writes held data.
+func (m *maskWriter) Flush() error {
+	return m.mask(true)
+}
+
+func (m *maskWriter) mask(final bool) error {
+	var out bytes.Buffer
+
+	i := 0
+
+scan:
+	for i < len(m.buf) {
+		rest := m.buf[i:]
+		for _, secret := range m.secrets {
+			switch {
+			case bytes.HasPrefix(rest, secret):
+				out.WriteString(Mask)
+				i += len(secret)
+				continue scan
+			case !final && len(rest) < len(secret) && bytes.HasPrefix(secret, rest):
+				break scan // wait for more data
+			}
+		}
+
+		out.WriteByte(m.buf[i])
+		i++
+	}
+
+	m.buf = append(m.buf[:0], m.buf[i:]...)
+
+	if out.Len() == 0 {
+		return nil
+	}
+
+	_, err := m.w.Write(out.Bytes())
+
+	return err
+}
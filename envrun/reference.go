@@ -0,0 +1,95 @@
+// Package envrun allows to run child processes with environment variables taken from KeepassXC database.
+package envrun
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ReferenceScheme is an URL scheme of references to KeepassXC entries.
+const ReferenceScheme = "keepassxc"
+
+// Fields which may be referenced.
+const (
+	FieldPassword = "password"
+	FieldLogin    = "login"
+	FieldTOTP     = "totp"
+)
+
+// Reference points to a field of KeepassXC entry.
+// Text form is "keepassxc://[login@]host[:port][/path][#field]", i.e. "keepassxc://admin@db.internal#password".
+type Reference struct {
+	// URL is used to query logins from KeepassXC. Scheme is always "https".
+	URL string
+
+	// Login is used to choose entry if multiple ones found by URL. Optional.
+	Login string
+
+	// Field is an entry field value taken from. Default is FieldPassword.
+	Field string
+}
+
+// IsReference checks if string looks like a reference to KeepassXC entry.
+func IsReference(s string) bool {
+	return strings.HasPrefix(s, ReferenceScheme+"://")
+}
+
+// ParseReference parses reference from text form.
+func ParseReference(s string) (Reference, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return Reference{}, fmt.Errorf("parse reference: %w", err)
+	}
+
+	if u.Scheme != ReferenceScheme {
+		return Reference{}, fmt.Errorf("reference must have scheme %q, got %q", ReferenceScheme, u.Scheme)
+	}
+
+	if u.Host == "" {
+		return Reference{}, fmt.Errorf("reference %q has no host", s)
+	}
+
+	ref := Reference{
+		Field: u.Fragment,
+	}
+
+	switch ref.Field {
+	case "":
+		ref.Field = FieldPassword
+	case "username":
+		ref.Field = FieldLogin
+	case FieldPassword, FieldLogin, FieldTOTP:
+	default:
+		return Reference{}, fmt.Errorf("reference %q has unknown field %q", s, ref.Field)
+	}
+
+	if u.User != nil {
+		ref.Login = u.User.Username()
+	}
+
+	lookupURL := url.URL{
+		Scheme:   "https",
+		Host:     u.Host,
+		Path:     u.Path,
+		RawQuery: u.RawQuery,
+	}
+	ref.URL = lookupURL.String()
+
+	return ref, nil
+}
+
+func (r Reference) String() string {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return r.URL
+	}
+
+	u.Scheme = ReferenceScheme
+	if r.Login != "" {
+		u.User = url.User(r.Login)
+	}
+	u.Fragment = r.Field
+
+	return u.String()
+}
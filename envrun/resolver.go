@@ -0,0 +1,82 @@
+package envrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/xakep666/gkpxc"
+)
+
+var (
+	// ErrNoEntries returned if no entries found for reference.
+	ErrNoEntries = errors.New("no entries found")
+
+	// ErrAmbiguousReference returned if multiple entries found for reference.
+	ErrAmbiguousReference = errors.New("multiple entries found, specify login in reference")
+)
+
+// Resolver fetches values of references from KeepassXC. Association credentials must be set for client.
+type Resolver struct {
+	Client *gkpxc.Client
+
+	logins map[string][]gkpxc.LoginEntry // cache to not request same url multiple times
+}
+
+// Resolve returns referenced field value.
+func (r *Resolver) Resolve(ctx context.Context, ref Reference) (string, error) {
+	entry, err := r.lookup(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", ref, err)
+	}
+
+	switch ref.Field {
+	case FieldLogin:
+		return entry.Login, nil
+	case FieldTOTP:
+		totp, err := r.Client.GetTOTP(ctx, gkpxc.GetTOTPRequest{UUID: entry.UUID})
+		if err != nil {
+			return "", fmt.Errorf("resolve %s: get totp: %w", ref, err)
+		}
+
+		return totp.TOTP, nil
+	default:
+		return entry.Password, nil
+	}
+}
+
+func (r *Resolver) lookup(ctx context.Context, ref Reference) (gkpxc.LoginEntry, error) {
+	entries, ok := r.logins[ref.URL]
+	if !ok {
+		logins, err := r.Client.GetLogins(ctx, gkpxc.GetLoginsRequest{URL: ref.URL})
+		switch {
+		case errors.Is(err, nil):
+			entries = logins.Entries
+		case gkpxc.IsErrorCode(err, gkpxc.ErrCodeNoLoginsFound):
+		default:
+			return gkpxc.LoginEntry{}, err
+		}
+
+		if r.logins == nil {
+			r.logins = make(map[string][]gkpxc.LoginEntry)
+		}
+
+		r.logins[ref.URL] = entries
+	}
+
+	var found []gkpxc.LoginEntry
+	for _, entry := range entries {
+		if ref.Login == "" || entry.Login == ref.Login {
+			found = append(found, entry)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return gkpxc.LoginEntry{}, ErrNoEntries
+	case 1:
+		return found[0], nil
+	default:
+		return gkpxc.LoginEntry{}, ErrAmbiguousReference
+	}
+}
@@ -0,0 +1,105 @@
+package envrun
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+)
+
+// Runner runs child process with resolved environment variables.
+type Runner struct {
+	Resolver *Resolver
+
+	// Mask enables replacing of resolved secret values in child's stdout and stderr with Mask.
+	Mask bool
+
+	// Stdin, Stdout, Stderr are passed to child process. Current process ones used if nil.
+	Stdin          io.Reader
+	Stdout, Stderr io.Writer
+}
+
+// Run resolves references in variables and runs command with them added to current process environment.
+// Signals received by current process forwarded to child.
+// If command exits with non-zero code *exec.ExitError returned.
+func (r *Runner) Run(ctx context.Context, vars []Variable, name string, args ...string) error {
+	env := os.Environ()
+
+	var secrets []string
+
+	for _, v := range vars {
+		value := v.Value
+		if IsReference(value) {
+			ref, err := ParseReference(value)
+			if err != nil {
+				return fmt.Errorf("variable %s: %w", v.Name, err)
+			}
+
+			if value, err = r.Resolver.Resolve(ctx, ref); err != nil {
+				return fmt.Errorf("variable %s: %w", v.Name, err)
+			}
+
+			secrets = append(secrets, value)
+		}
+
+		env = append(env, v.Name+"="+value)
+	}
+
+	cmd := exec.Command(name, args...)
+	cmd.Env = env
+	cmd.Stdin = r.Stdin
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+
+	stdout, stderr := r.Stdout, r.Stderr
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+
+	if r.Mask {
+		maskedStdout, maskedStderr := newMaskWriter(stdout, secrets), newMaskWriter(stderr, secrets)
+		defer maskedStdout.Flush()
+		defer maskedStderr.Flush()
+
+		stdout, stderr = maskedStdout, maskedStderr
+	}
+
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start command: %w", err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-signals:
+				_ = cmd.Process.Signal(sig)
+			}
+		}
+	}()
+
+	return cmd.Wait()
+}
+
+// ExitCode returns code to exit current process with to propagate child's exit status like shells do:
+// child's exit code or 128+signal number if child was killed by signal.
+func ExitCode(err *exec.ExitError) int {
+	return exitCode(err)
+}
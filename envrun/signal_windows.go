@@ -0,0 +1,13 @@
+package envrun
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Windows doesn't support sending signals to processes, but interrupt must be caught to not kill parent before child.
+var forwardedSignals = []os.Signal{
+	os.Interrupt,
+}
+
+func exitCode(err *exec.ExitError) int { return err.ExitCode() }
@@ -0,0 +1,190 @@
+package gkpxc
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Extra holds message fields not modeled by library, i.e. added in newer KeepassXC.
+type Extra map[string]json.RawMessage
+
+// Get unmarshals field value. It returns false if field is absent.
+func (e Extra) Get(name string, to interface{}) (bool, error) {
+	raw, ok := e[name]
+	if !ok {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(raw, to)
+}
+
+var knownFieldsCache sync.Map // reflect.Type -> map[string]struct{}
+
+// unmarshalWithExtra unmarshals data to v (pointer to struct without own UnmarshalJSON)
+// and puts fields not known by v to extra. Only unknown fields are copied so known values (i.e. passwords)
+// are not duplicated in memory.
+func unmarshalWithExtra(data []byte, v interface{}, extra *Extra) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	known := knownFields(reflect.TypeOf(v).Elem())
+
+	*extra = nil
+
+	// data is valid JSON here so it's scanned without validation
+	s := jsonScanner{data: data}
+	if !s.consume('{') {
+		return nil // null
+	}
+
+	for s.pos < len(s.data) && !s.consume('}') {
+		rawName := s.value()
+		s.consume(':')
+		value := s.value()
+		s.consume(',')
+
+		var name string
+		if err := json.Unmarshal(rawName, &name); err != nil {
+			return err
+		}
+
+		if _, ok := known[strings.ToLower(name)]; ok {
+			continue
+		}
+
+		if *extra == nil {
+			*extra = make(Extra)
+		}
+
+		(*extra)[name] = append(json.RawMessage(nil), value...)
+	}
+
+	return nil
+}
+
+// jsonScanner walks over valid JSON object without decoding values.
+type jsonScanner struct {
+	data []byte
+	pos  int
+}
+
+// consume skips c if it's next non-space character.
+func (s *jsonScanner) consume(c byte) bool {
+	s.skipSpace()
+
+	if s.pos < len(s.data) && s.data[s.pos] == c {
+		s.pos++
+		return true
+	}
+
+	return false
+}
+
+// value skips next value and returns its raw representation.
+func (s *jsonScanner) value() []byte {
+	s.skipSpace()
+
+	start, depth, inString := s.pos, 0, false
+
+	for ; s.pos < len(s.data); s.pos++ {
+		c := s.data[s.pos]
+
+		switch {
+		case inString:
+			if c == '\\' {
+				s.pos++
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			if depth == 0 {
+				return s.data[start:s.pos] // end of enclosing object
+			}
+
+			depth--
+		case depth == 0 && (c == ',' || c == ':' || isSpace(c)):
+			return s.data[start:s.pos]
+		}
+	}
+
+	return s.data[start:]
+}
+
+func (s *jsonScanner) skipSpace() {
+	for s.pos < len(s.data) && isSpace(s.data[s.pos]) {
+		s.pos++
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// marshalWithExtra marshals v (struct without own MarshalJSON) and adds extra fields. Known fields are not overridden.
+func marshalWithExtra(v interface{}, extra Extra) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for name, value := range extra {
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+// knownFields returns lower-cased JSON names of struct fields including embedded ones
+// (encoding/json matches names case-insensitively).
+func knownFields(t reflect.Type) map[string]struct{} {
+	if cached, ok := knownFieldsCache.Load(t); ok {
+		return cached.(map[string]struct{})
+	}
+
+	known := make(map[string]struct{})
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for embedded := range knownFields(field.Type) {
+				known[embedded] = struct{}{}
+			}
+
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		known[strings.ToLower(name)] = struct{}{}
+	}
+
+	knownFieldsCache.Store(t, known)
+
+	return known
+}
@@ -0,0 +1,84 @@
+package gkpxc
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUnmarshalWithExtra(t *testing.T) {
+	var resp GetLoginsResponse
+
+	data := `{"count":1,"entries":[{"uuid":"1"}],"Success":"true","version":"2.8.0","passkeys":[{"id":1}]}`
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		t.Fatal("Unmarshal", err)
+	}
+
+	if resp.Count != 1 || len(resp.Entries) != 1 || resp.Success == nil || !*resp.Success {
+		t.Fatalf("Unexpected response %+v", resp)
+	}
+
+	if len(resp.Extra) != 2 || string(resp.Extra["version"]) != `"2.8.0"` {
+		t.Fatalf("Unexpected extra fields %v", resp.Extra)
+	}
+
+	var passkeys []map[string]int
+	if ok, err := resp.Extra.Get("passkeys", &passkeys); !ok || err != nil || passkeys[0]["id"] != 1 {
+		t.Fatalf("Unexpected passkeys %v: %t, %v", passkeys, ok, err)
+	}
+
+	if ok, err := resp.Extra.Get("absent", &passkeys); ok || err != nil {
+		t.Fatalf("Unexpected absent field: %t, %v", ok, err)
+	}
+}
+
+func TestMessage_Extra(t *testing.T) {
+	var msg Message
+
+	data := `{"action":"database-locked","nonce":null,"clientID":null,"requestID":"1","reason":"idle"}`
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		t.Fatal("Unmarshal", err)
+	}
+
+	if msg.Action != "database-locked" || msg.RequestID != "1" || string(msg.Extra["reason"]) != `"idle"` || len(msg.Extra) != 1 {
+		t.Fatalf("Unexpected message %+v", msg)
+	}
+
+	msg.Extra["action"] = json.RawMessage(`"overridden"`)
+
+	marshaled, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal("Marshal", err)
+	}
+
+	var decoded Message
+	if err = json.Unmarshal(marshaled, &decoded); err != nil {
+		t.Fatal("Unmarshal", err)
+	}
+
+	if decoded.Action != "database-locked" || string(decoded.Extra["reason"]) != `"idle"` {
+		t.Fatalf("Unexpected message %s", marshaled)
+	}
+}
+
+func TestUnmarshalWithExtra_Scan(t *testing.T) {
+	var resp GetLoginsResponse
+
+	data := ` { "entries" : [ {"uuid":"1","password":"}],\"{"} ] , "a\"b":{"c":[1,{"d":"]"}]} ,"n": -1.5e3,
+		"Count":1,"t":true }`
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		t.Fatal("Unmarshal", err)
+	}
+
+	if resp.Count != 1 || len(resp.Entries) != 1 || resp.Entries[0].Password != `}],"{` {
+		t.Fatalf("Unexpected response %+v", resp)
+	}
+
+	if len(resp.Extra) != 3 || string(resp.Extra[`a"b`]) != `{"c":[1,{"d":"]"}]}` ||
+		string(resp.Extra["n"]) != "-1.5e3" || string(resp.Extra["t"]) != "true" {
+		t.Fatalf("Unexpected extra fields %v", resp.Extra)
+	}
+
+	if err := json.Unmarshal([]byte(`{}`), &resp); err != nil || resp.Extra != nil {
+		t.Fatalf("Unexpected extra fields %v: %v", resp.Extra, err)
+	}
+}
@@ -0,0 +1,41 @@
+package gkpxc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/gkpxctest"
+)
+
+func TestClient_Extra(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	srv.AddEntry(gkpxctest.Entry{URL: "https://example.com", Login: "user", Password: "pass"})
+
+	messages := make(chan gkpxc.Message, 1)
+	client := gkpxctest.NewAssociatedClient(t, srv, gkpxc.WithUnsolicitedMessageHandler(func(msg gkpxc.Message) {
+		messages <- msg
+	}))
+
+	logins, err := client.GetLogins(context.Background(), gkpxc.GetLoginsRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatal("GetLogins", err)
+	}
+
+	var version string
+	if ok, err := logins.Extra.Get("version", &version); !ok || err != nil || version != gkpxctest.Version {
+		t.Fatalf("Unexpected version %s in extra %v", version, logins.Extra)
+	}
+
+	if _, ok := logins.Extra["nonce"]; !ok {
+		t.Fatalf("Nonce not found in extra %v", logins.Extra)
+	}
+
+	srv.SetLocked(true, false)
+
+	if msg := <-messages; msg.Action != "database-locked" {
+		t.Fatalf("Unexpected message %+v", msg)
+	}
+}
@@ -0,0 +1,413 @@
+// Package filestore provides encrypted file storage for association credentials.
+// It implements keyring.Keyring so it may be used on systems without OS keyring (i.e. headless Linux).
+//
+// Items are encrypted with NaCl secretbox using key derived from passphrase, key file or systemd credential.
+// Store file and its directory are created with owner-only permissions, store refuses to read file
+// accessible by other users. Modifications are serialized between processes with advisory lock
+// on sidecar "<path>.lock" file.
+package filestore
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/99designs/keyring"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+const (
+	formatVersion = 1
+	keySize       = 32
+	saltSize      = 32
+	nonceSize     = 24
+)
+
+var (
+	// ErrInsecurePermissions returned if store or key file may be accessed by other users.
+	ErrInsecurePermissions = errors.New("insecure file permissions")
+
+	// ErrDecryptFailed returned if store can't be decrypted with provided key.
+	ErrDecryptFailed = errors.New("store decryption failed, wrong key?")
+
+	// ErrUnsupportedVersion returned if store file was written by newer version.
+	ErrUnsupportedVersion = errors.New("unsupported store file version")
+
+	// ErrKDFMismatch returned if store was encrypted using another key source type.
+	ErrKDFMismatch = errors.New("store encrypted with another key source")
+)
+
+// storeFile is an on-disk representation of store.
+type storeFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Source  string `json:"source,omitempty"` // absent in files written by older versions
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// storeItem is an encrypted representation of keyring.Item.
+type storeItem struct {
+	Data             []byte    `json:"data"`
+	Label            string    `json:"label,omitempty"`
+	Description      string    `json:"description,omitempty"`
+	ModificationTime time.Time `json:"modificationTime"`
+}
+
+// Store keeps items encrypted in single file. Items are usually association credentials keyed by database hash.
+// File is re-read on every operation and modifications are done under file lock so multiple processes may share it.
+// Store is safe for concurrent use.
+type Store struct {
+	path      string
+	keySource KeySource
+
+	mu   sync.Mutex
+	salt []byte
+	key  *[keySize]byte
+}
+
+var _ keyring.Keyring = (*Store)(nil)
+
+// Open creates store backed by file on path. File is created on first Set.
+// Key is derived on first access so passphrase is not requested if store is not used.
+func Open(path string, keySource KeySource) (*Store, error) {
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, nil):
+		if err = checkPermissions(path, info); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, os.ErrNotExist):
+	default:
+		return nil, fmt.Errorf("stat store: %w", err)
+	}
+
+	return &Store{path: path, keySource: keySource}, nil
+}
+
+// Get returns item by key.
+func (s *Store) Get(key string) (keyring.Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, err := s.load()
+	if err != nil {
+		return keyring.Item{}, err
+	}
+
+	item, ok := items[key]
+	if !ok {
+		return keyring.Item{}, keyring.ErrKeyNotFound
+	}
+
+	return item.keyringItem(key), nil
+}
+
+// GetMetadata returns item and its modification time.
+func (s *Store) GetMetadata(key string) (keyring.Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, err := s.load()
+	if err != nil {
+		return keyring.Metadata{}, err
+	}
+
+	item, ok := items[key]
+	if !ok {
+		return keyring.Metadata{}, keyring.ErrKeyNotFound
+	}
+
+	kItem := item.keyringItem(key)
+
+	return keyring.Metadata{Item: &kItem, ModificationTime: item.ModificationTime}, nil
+}
+
+// Set creates or replaces item.
+func (s *Store) Set(item keyring.Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	items, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	items[item.Key] = storeItem{
+		Data:             item.Data,
+		Label:            item.Label,
+		Description:      item.Description,
+		ModificationTime: time.Now(),
+	}
+
+	return s.save(items)
+}
+
+// Remove removes item by key.
+func (s *Store) Remove(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	items, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := items[key]; !ok {
+		return keyring.ErrKeyNotFound
+	}
+
+	delete(items, key)
+
+	return s.save(items)
+}
+
+// Keys returns sorted keys of all items.
+func (s *Store) Keys() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// Close wipes cached key. Store may be used after Close, key is derived again then.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.setKey(nil, nil)
+
+	return nil
+}
+
+func (i storeItem) keyringItem(key string) keyring.Item {
+	return keyring.Item{Key: key, Data: i.Data, Label: i.Label, Description: i.Description}
+}
+
+func (s *Store) load() (map[string]storeItem, error) {
+	items := make(map[string]storeItem)
+
+	f, err := os.Open(s.path)
+	switch {
+	case errors.Is(err, nil):
+	case errors.Is(err, os.ErrNotExist):
+		return items, nil
+	default:
+		return nil, fmt.Errorf("read store: %w", err)
+	}
+
+	defer f.Close()
+
+	// file may be replaced after Open
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat store: %w", err)
+	}
+
+	if err = checkPermissions(s.path, info); err != nil {
+		return nil, err
+	}
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("read store: %w", err)
+	}
+
+	var file storeFile
+	if err = json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("parse store %s: %w", s.path, err)
+	}
+
+	if file.Version != formatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, file.Version)
+	}
+
+	if file.KDF != s.keySource.KDF() {
+		return nil, fmt.Errorf("%w: store uses %s, got %s", ErrKDFMismatch, file.KDF, s.keySource.KDF())
+	}
+
+	if len(file.Nonce) != nonceSize {
+		return nil, fmt.Errorf("parse store %s: invalid nonce size", s.path)
+	}
+
+	key, err := s.deriveKey(file.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce [nonceSize]byte
+	copy(nonce[:], file.Nonce)
+
+	plain, ok := secretbox.Open(nil, file.Data, &nonce, key)
+	switch {
+	case ok:
+	case file.Source != "" && file.Source != s.keySource.Source():
+		return nil, fmt.Errorf("%w: store encrypted using %s, got %s", ErrDecryptFailed, file.Source, s.keySource.Source())
+	default:
+		return nil, ErrDecryptFailed
+	}
+
+	defer wipe(plain)
+
+	if err = json.Unmarshal(plain, &items); err != nil {
+		return nil, fmt.Errorf("parse store items: %w", err)
+	}
+
+	return items, nil
+}
+
+func (s *Store) save(items map[string]storeItem) error {
+	if s.salt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("generate salt: %w", err)
+		}
+
+		if _, err := s.deriveKey(salt); err != nil {
+			return err
+		}
+	}
+
+	var nonce [nonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return fmt.Errorf("generate nonce: %w", err)
+	}
+
+	plain, err := json.Marshal(items)
+	if err != nil {
+		return fmt.Errorf("serialize store items: %w", err)
+	}
+
+	defer wipe(plain)
+
+	content, err := json.Marshal(storeFile{
+		Version: formatVersion,
+		KDF:     s.keySource.KDF(),
+		Source:  s.keySource.Source(),
+		Salt:    s.salt,
+		Nonce:   nonce[:],
+		Data:    secretbox.Seal(nil, plain, &nonce, s.key),
+	})
+	if err != nil {
+		return fmt.Errorf("serialize store: %w", err)
+	}
+
+	return writeFile(s.path, content)
+}
+
+// lock takes exclusive lock on sidecar file so modifications made by other processes between load and save
+// are not lost. Returned function releases lock.
+func (s *Store) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return nil, fmt.Errorf("create store dir: %w", err)
+	}
+
+	f, err := os.OpenFile(s.path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+
+	if err = lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("lock store: %w", err)
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// deriveKey derives key for salt. Key is cached so it's derived once if salt is not changed.
+func (s *Store) deriveKey(salt []byte) (*[keySize]byte, error) {
+	if s.key != nil && string(s.salt) == string(salt) {
+		return s.key, nil
+	}
+
+	key, err := s.keySource.DeriveKey(salt)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+
+	s.setKey(salt, key)
+
+	return key, nil
+}
+
+// setKey replaces cached key wiping previous one.
+func (s *Store) setKey(salt []byte, key *[keySize]byte) {
+	if s.key != nil && s.key != key {
+		wipe(s.key[:])
+	}
+
+	s.salt, s.key = salt, key
+}
+
+// writeFile atomically replaces file content. File is created with owner-only permissions.
+func writeFile(path string, content []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create store dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write store: %w", err)
+	}
+
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync store: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("close store: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace store: %w", err)
+	}
+
+	return nil
+}
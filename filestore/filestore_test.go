@@ -0,0 +1,283 @@
+package filestore_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/99designs/keyring"
+
+	"github.com/xakep666/gkpxc/filestore"
+)
+
+func passphrase(p string) filestore.KeySource {
+	return filestore.Passphrase(func() (string, error) { return p, nil })
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store", "associations.json")
+
+	prompts := 0
+	store, err := filestore.Open(path, filestore.Passphrase(func() (string, error) {
+		prompts++
+		return "secret", nil
+	}))
+	if err != nil {
+		t.Fatal("Open", err)
+	}
+
+	if _, err = store.Get("hash1"); !errors.Is(err, keyring.ErrKeyNotFound) {
+		t.Fatalf("Unexpected error %v, expected ErrKeyNotFound", err)
+	}
+
+	for _, item := range []keyring.Item{{Key: "hash1", Data: []byte("cred1")}, {Key: "hash2", Data: []byte("cred2")}} {
+		if err = store.Set(item); err != nil {
+			t.Fatal("Set", err)
+		}
+	}
+
+	if prompts != 1 {
+		t.Fatalf("Passphrase must be requested once, got %d", prompts)
+	}
+
+	if err = store.Close(); err != nil {
+		t.Fatal("Close", err)
+	}
+
+	// key wiped on close must be derived again
+	if _, err = store.Get("hash1"); err != nil {
+		t.Fatal("Get", err)
+	}
+
+	if prompts != 2 {
+		t.Fatalf("Passphrase must be requested again after Close, got %d requests", prompts)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal("Stat", err)
+		}
+
+		if info.Mode().Perm() != 0o600 {
+			t.Fatalf("Unexpected store file mode %#o", info.Mode().Perm())
+		}
+	}
+
+	// new instance to ensure that content read from disk
+	store, err = filestore.Open(path, passphrase("secret"))
+	if err != nil {
+		t.Fatal("Open", err)
+	}
+
+	item, err := store.Get("hash2")
+	if err != nil {
+		t.Fatal("Get", err)
+	}
+
+	if string(item.Data) != "cred2" {
+		t.Fatalf("Unexpected data %q", item.Data)
+	}
+
+	if err = store.Remove("hash1"); err != nil {
+		t.Fatal("Remove", err)
+	}
+
+	keys, err := store.Keys()
+	if err != nil {
+		t.Fatal("Keys", err)
+	}
+
+	if len(keys) != 1 || keys[0] != "hash2" {
+		t.Fatalf("Unexpected keys %v", keys)
+	}
+
+	store, err = filestore.Open(path, passphrase("wrong"))
+	if err != nil {
+		t.Fatal("Open", err)
+	}
+
+	if _, err = store.Get("hash2"); !errors.Is(err, filestore.ErrDecryptFailed) {
+		t.Fatalf("Unexpected error %v, expected ErrDecryptFailed", err)
+	}
+
+	store, err = filestore.Open(path, filestore.KeyFile(path))
+	if err != nil {
+		t.Fatal("Open", err)
+	}
+
+	if _, err = store.Get("hash2"); !errors.Is(err, filestore.ErrKDFMismatch) {
+		t.Fatalf("Unexpected error %v, expected ErrKDFMismatch", err)
+	}
+}
+
+func TestStore_KeyFile(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key")
+
+	if err := os.WriteFile(keyPath, []byte("short"), 0o600); err != nil {
+		t.Fatal("Write key", err)
+	}
+
+	store, err := filestore.Open(filepath.Join(dir, "store.json"), filestore.KeyFile(keyPath))
+	if err != nil {
+		t.Fatal("Open", err)
+	}
+
+	if err = store.Set(keyring.Item{Key: "hash", Data: []byte("cred")}); !errors.Is(err, filestore.ErrKeyTooShort) {
+		t.Fatalf("Unexpected error %v, expected ErrKeyTooShort", err)
+	}
+
+	if err = os.WriteFile(keyPath, []byte("0123456789abcdef0123456789abcdef"), 0o600); err != nil {
+		t.Fatal("Write key", err)
+	}
+
+	if err = store.Set(keyring.Item{Key: "hash", Data: []byte("cred")}); err != nil {
+		t.Fatal("Set", err)
+	}
+
+	t.Setenv(filestore.CredentialsDirectoryEnv, dir)
+
+	store, err = filestore.Open(filepath.Join(dir, "store.json"), filestore.SystemdCredential("key"))
+	if err != nil {
+		t.Fatal("Open", err)
+	}
+
+	if _, err = store.Get("hash"); err != nil {
+		t.Fatal("Get", err)
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, "other"), []byte("fedcba9876543210fedcba9876543210"), 0o600); err != nil {
+		t.Fatal("Write key", err)
+	}
+
+	store, err = filestore.Open(filepath.Join(dir, "store.json"), filestore.SystemdCredential("other"))
+	if err != nil {
+		t.Fatal("Open", err)
+	}
+
+	// another key from source of other type, error must mention both
+	_, err = store.Get("hash")
+	if !errors.Is(err, filestore.ErrDecryptFailed) ||
+		!strings.Contains(err.Error(), filestore.SourceKeyFile) || !strings.Contains(err.Error(), filestore.SourceSystemdCredential) {
+		t.Fatalf("Unexpected error %v, expected ErrDecryptFailed with key sources", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	if err = os.Chmod(keyPath, 0o644); err != nil {
+		t.Fatal("Chmod", err)
+	}
+
+	store, err = filestore.Open(filepath.Join(dir, "store.json"), filestore.KeyFile(keyPath))
+	if err != nil {
+		t.Fatal("Open", err)
+	}
+
+	if _, err = store.Get("hash"); !errors.Is(err, filestore.ErrInsecurePermissions) {
+		t.Fatalf("Unexpected error %v, expected ErrInsecurePermissions", err)
+	}
+}
+
+func TestOpen_Insecure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions not checked on windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "store.json")
+	if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+		t.Fatal("Write", err)
+	}
+
+	if _, err := filestore.Open(path, passphrase("secret")); !errors.Is(err, filestore.ErrInsecurePermissions) {
+		t.Fatalf("Unexpected error %v, expected ErrInsecurePermissions", err)
+	}
+}
+
+func TestStore_Insecure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions not checked on windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "store.json")
+
+	store, err := filestore.Open(path, passphrase("secret"))
+	if err != nil {
+		t.Fatal("Open", err)
+	}
+
+	if err = store.Set(keyring.Item{Key: "hash", Data: []byte("cred")}); err != nil {
+		t.Fatal("Set", err)
+	}
+
+	// permissions changed after Open
+	if err = os.Chmod(path, 0o644); err != nil {
+		t.Fatal("Chmod", err)
+	}
+
+	if _, err = store.Get("hash"); !errors.Is(err, filestore.ErrInsecurePermissions) {
+		t.Fatalf("Unexpected error %v, expected ErrInsecurePermissions", err)
+	}
+
+	if err = store.Set(keyring.Item{Key: "hash2", Data: []byte("cred")}); !errors.Is(err, filestore.ErrInsecurePermissions) {
+		t.Fatalf("Unexpected error %v, expected ErrInsecurePermissions", err)
+	}
+}
+
+func TestStore_MultipleProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+
+	// every store has own file descriptors like separate process does
+	stores := make([]*filestore.Store, 4)
+	for i := range stores {
+		store, err := filestore.Open(path, passphrase("secret"))
+		if err != nil {
+			t.Fatal("Open", err)
+		}
+
+		stores[i] = store
+	}
+
+	const itemsPerStore = 5
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, len(stores)*itemsPerStore)
+
+	for i, store := range stores {
+		wg.Add(1)
+
+		go func(i int, store *filestore.Store) {
+			defer wg.Done()
+
+			for j := 0; j < itemsPerStore; j++ {
+				errs <- store.Set(keyring.Item{Key: fmt.Sprintf("hash%d-%d", i, j), Data: []byte("cred")})
+			}
+		}(i, store)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal("Set", err)
+		}
+	}
+
+	keys, err := stores[0].Keys()
+	if err != nil {
+		t.Fatal("Keys", err)
+	}
+
+	if len(keys) != len(stores)*itemsPerStore {
+		t.Fatalf("Lost updates, got keys %v", keys)
+	}
+}
@@ -0,0 +1,166 @@
+package filestore
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Key derivation functions recorded in store file header.
+const (
+	KDFScrypt = "scrypt"
+	KDFHKDF   = "hkdf-sha256"
+)
+
+// Key source names recorded in store file header.
+const (
+	SourcePassphrase        = "passphrase"
+	SourceKeyFile           = "keyfile"
+	SourceSystemdCredential = "systemd-credential"
+)
+
+// scrypt parameters. Work factor is lower than age uses (2^18) because helpers are started on every command.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// minKeyFileSize is a minimal size of key file content.
+const minKeyFileSize = 16
+
+// CredentialsDirectoryEnv is set by systemd to directory with decrypted service credentials.
+const CredentialsDirectoryEnv = "CREDENTIALS_DIRECTORY"
+
+var (
+	// ErrKeyTooShort returned if key file contains too little key material.
+	ErrKeyTooShort = fmt.Errorf("key must be at least %d bytes", minKeyFileSize)
+
+	// ErrNoCredentialsDirectory returned by SystemdCredential if process was not started with systemd credentials.
+	ErrNoCredentialsDirectory = errors.New(CredentialsDirectoryEnv + " is not set")
+)
+
+// KeySource provides encryption key for store.
+type KeySource interface {
+	// KDF returns name of key derivation function. It's recorded in store to detect key source change.
+	KDF() string
+
+	// Source returns name of key source. It's recorded in store to report key source change
+	// if store can't be decrypted, sources with the same KDF may be switched if they provide the same key.
+	Source() string
+
+	// DeriveKey derives encryption key using salt stored in file.
+	DeriveKey(salt []byte) (*[keySize]byte, error)
+}
+
+// PassphraseFunc returns passphrase used to derive key.
+type PassphraseFunc func() (string, error)
+
+// Passphrase derives key from passphrase using scrypt.
+func Passphrase(f PassphraseFunc) KeySource { return passphraseSource(f) }
+
+type passphraseSource PassphraseFunc
+
+func (passphraseSource) KDF() string { return KDFScrypt }
+
+func (passphraseSource) Source() string { return SourcePassphrase }
+
+func (f passphraseSource) DeriveKey(salt []byte) (*[keySize]byte, error) {
+	passphrase, err := f()
+	if err != nil {
+		return nil, fmt.Errorf("get passphrase: %w", err)
+	}
+
+	if passphrase == "" {
+		return nil, fmt.Errorf("empty passphrase")
+	}
+
+	// passphrase string can't be wiped but its copies can
+	secret := []byte(passphrase)
+	defer wipe(secret)
+
+	derived, err := scrypt.Key(secret, salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("scrypt: %w", err)
+	}
+
+	defer wipe(derived)
+
+	var key [keySize]byte
+	copy(key[:], derived)
+
+	return &key, nil
+}
+
+// KeyFile derives key from file content using HKDF. File must contain at least 16 bytes of random data
+// (i.e. created with "head -c 32 /dev/urandom > key") and must not be accessible by other users.
+func KeyFile(path string) KeySource { return keyFileSource(path) }
+
+type keyFileSource string
+
+func (keyFileSource) KDF() string { return KDFHKDF }
+
+func (keyFileSource) Source() string { return SourceKeyFile }
+
+func (s keyFileSource) DeriveKey(salt []byte) (*[keySize]byte, error) {
+	path := string(s)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat key file: %w", err)
+	}
+
+	if err = checkPermissions(path, info); err != nil {
+		return nil, err
+	}
+
+	secret, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+
+	defer wipe(secret)
+
+	if len(secret) < minKeyFileSize {
+		return nil, fmt.Errorf("%s: %w", path, ErrKeyTooShort)
+	}
+
+	var key [keySize]byte
+	if _, err = io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte("gkpxc filestore")), key[:]); err != nil {
+		return nil, fmt.Errorf("hkdf: %w", err)
+	}
+
+	return &key, nil
+}
+
+// SystemdCredential derives key from systemd service credential with given name. Credential may be sealed
+// with "systemd-creds encrypt" and passed to service with LoadCredentialEncrypted= directive, systemd decrypts it
+// to CREDENTIALS_DIRECTORY which is readable only by service.
+func SystemdCredential(name string) KeySource { return systemdCredentialSource(name) }
+
+type systemdCredentialSource string
+
+func (systemdCredentialSource) KDF() string { return KDFHKDF }
+
+func (systemdCredentialSource) Source() string { return SourceSystemdCredential }
+
+func (s systemdCredentialSource) DeriveKey(salt []byte) (*[keySize]byte, error) {
+	dir := os.Getenv(CredentialsDirectoryEnv)
+	if dir == "" {
+		return nil, ErrNoCredentialsDirectory
+	}
+
+	return keyFileSource(filepath.Join(dir, string(s))).DeriveKey(salt)
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package filestore
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkPermissions ensures that file is owned by current user and not accessible by others.
+func checkPermissions(path string, info os.FileInfo) error {
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return fmt.Errorf("%w: %s has mode %#o, expected %#o", ErrInsecurePermissions, path, perm, perm&^0o077)
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%w: %s owned by uid %d", ErrInsecurePermissions, path, stat.Uid)
+	}
+
+	return nil
+}
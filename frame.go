@@ -0,0 +1,98 @@
+package gkpxc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxMessageSize is a default limit of message received from KeepassXC.
+const DefaultMaxMessageSize = 16 << 20
+
+var (
+	// ErrMessageTooLarge returned if message received from KeepassXC exceeds limit set by WithMaxMessageSize.
+	ErrMessageTooLarge = fmt.Errorf("message too large")
+
+	// ErrInvalidMessage passed to async error handlers if malformed message received.
+	// Such messages are skipped.
+	ErrInvalidMessage = fmt.Errorf("invalid message")
+
+	// ErrConnectionLost returned to pending and following requests after connection closed or broken.
+	ErrConnectionLost = fmt.Errorf("connection lost")
+)
+
+// frameReader splits stream to JSON objects without decoding them. Data between objects is skipped.
+type frameReader struct {
+	r       *bufio.Reader
+	maxSize int
+	buf     []byte
+}
+
+func newFrameReader(r io.Reader, maxSize int) *frameReader {
+	return &frameReader{r: bufio.NewReader(r), maxSize: maxSize}
+}
+
+// next returns next frame valid until following call. Error wrapping ErrInvalidMessage is returned
+// for skipped data, stream may be read further then. Other errors are permanent.
+func (f *frameReader) next() ([]byte, error) {
+	skipped := 0
+
+	for {
+		b, err := f.r.ReadByte()
+		switch {
+		case errors.Is(err, io.EOF) && skipped > 0:
+			return nil, io.ErrUnexpectedEOF
+		case err != nil:
+			return nil, err
+		case b == '{' && skipped > 0:
+			_ = f.r.UnreadByte()
+			return nil, fmt.Errorf("%w: %d bytes skipped before message", ErrInvalidMessage, skipped)
+		case b == '{':
+		case b == ' ', b == '\t', b == '\r', b == '\n':
+			continue
+		default:
+			skipped++
+			continue
+		}
+
+		break
+	}
+
+	f.buf = append(f.buf[:0], '{')
+
+	var (
+		depth            = 1
+		inString, escape bool
+	)
+
+	for depth > 0 {
+		b, err := f.r.ReadByte()
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil, io.ErrUnexpectedEOF
+		case err != nil:
+			return nil, err
+		case len(f.buf) >= f.maxSize:
+			return nil, fmt.Errorf("%w: limit is %d bytes", ErrMessageTooLarge, f.maxSize)
+		}
+
+		f.buf = append(f.buf, b)
+
+		switch {
+		case escape:
+			escape = false
+		case inString && b == '\\':
+			escape = true
+		case b == '"':
+			inString = !inString
+		case inString:
+		case b == '{', b == '[':
+			depth++
+		case b == '}', b == ']':
+			depth--
+		}
+	}
+
+	return f.buf, nil
+}
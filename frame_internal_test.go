@@ -0,0 +1,86 @@
+package gkpxc
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFrameReader(t *testing.T) {
+	input := `{"action":"a","text":"}{\"\\"} ` + "\n" + `garbage{"action":"b","nested":[{"x":[1]}]}{"action":"c"`
+
+	frames := newFrameReader(strings.NewReader(input), 64)
+
+	frame, err := frames.next()
+	if err != nil || string(frame) != `{"action":"a","text":"}{\"\\"}` {
+		t.Fatalf("Unexpected frame %s: %v", frame, err)
+	}
+
+	if _, err = frames.next(); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("Unexpected error %v, expected ErrInvalidMessage", err)
+	}
+
+	frame, err = frames.next()
+	if err != nil || string(frame) != `{"action":"b","nested":[{"x":[1]}]}` {
+		t.Fatalf("Unexpected frame %s: %v", frame, err)
+	}
+
+	if _, err = frames.next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Unexpected error %v, expected io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestFrameReader_too_large(t *testing.T) {
+	frames := newFrameReader(strings.NewReader(`{"action":"`+strings.Repeat("a", 100)+`"}`), 64)
+
+	if _, err := frames.next(); !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("Unexpected error %v, expected ErrMessageTooLarge", err)
+	}
+}
+
+func TestFrameReader_EOF(t *testing.T) {
+	frames := newFrameReader(strings.NewReader(" \n"), 64)
+
+	if _, err := frames.next(); !errors.Is(err, io.EOF) {
+		t.Fatalf("Unexpected error %v, expected io.EOF", err)
+	}
+}
+
+func FuzzFrameReader(f *testing.F) {
+	f.Add([]byte(`{"action":"database-locked"}`))
+	f.Add([]byte(`{"action":"get-logins","message":"AAAA","nonce":"AAAA"}` + "\n" + `{"errorCode":"15"}`))
+	f.Add([]byte(`{"a":"\"}\\"}xx{"b":[{}]}`))
+	f.Add([]byte(`{"action":`))
+	f.Add([]byte(`]]}}{{[[`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		const maxSize = 1024
+
+		frames := newFrameReader(strings.NewReader(string(data)), maxSize)
+
+		for i := 0; i <= len(data); i++ {
+			frame, err := frames.next()
+			if errors.Is(err, ErrInvalidMessage) {
+				continue
+			}
+
+			if err != nil {
+				return
+			}
+
+			if len(frame) > maxSize || frame[0] != '{' {
+				t.Fatalf("Invalid frame %q", frame)
+			}
+
+			// decode path must not panic on any frame
+			var msg Message
+			if json.Unmarshal(frame, &msg) == nil {
+				_ = msg.asError()
+			}
+		}
+
+		t.Fatalf("Frame reader didn't stop")
+	})
+}
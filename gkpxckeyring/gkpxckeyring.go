@@ -0,0 +1,123 @@
+// Package gkpxckeyring implements github.com/99designs/keyring.Keyring storing items in KeepassXC database.
+package gkpxckeyring
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/99designs/keyring"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/internal/bootstrap"
+	"github.com/xakep666/gkpxc/secretstore"
+)
+
+// Metadata keys used to store non-secret item parts.
+const (
+	metaLabel            = "label"
+	metaDescription      = "description"
+	metaModificationTime = "modified"
+)
+
+// Keyring stores items as secretstore.Store secrets.
+type Keyring struct {
+	store  *secretstore.Store
+	client *gkpxc.Client // closed by Close if set
+}
+
+var _ keyring.Keyring = (*Keyring)(nil)
+
+// New creates keyring on top of secret store.
+func New(store *secretstore.Store) *Keyring {
+	return &Keyring{store: store}
+}
+
+// Open connects to KeepassXC and creates keyring storing items in given namespace (see secretstore.Store).
+// Association credentials taken from (and new ones stored to) associations keyring.
+// Keyring must be closed after usage.
+func Open(ctx context.Context, namespace string, associations keyring.Keyring, opts ...gkpxc.ClientOption) (*Keyring, error) {
+	client, err := bootstrap.Connect(ctx, associations, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Keyring{
+		store:  &secretstore.Store{Client: client, Namespace: namespace},
+		client: client,
+	}, nil
+}
+
+func (k *Keyring) Get(key string) (keyring.Item, error) {
+	secret, err := k.get(key)
+	if err != nil {
+		return keyring.Item{}, err
+	}
+
+	item := itemFromMeta(key, secret.Meta)
+	item.Data = secret.Value
+
+	return item, nil
+}
+
+func (k *Keyring) GetMetadata(key string) (keyring.Metadata, error) {
+	secret, err := k.get(key)
+	if err != nil {
+		return keyring.Metadata{}, err
+	}
+
+	item := itemFromMeta(key, secret.Meta)
+	modTime, _ := time.Parse(time.RFC3339Nano, secret.Meta[metaModificationTime])
+
+	return keyring.Metadata{
+		Item:             &item,
+		ModificationTime: modTime,
+	}, nil
+}
+
+func (k *Keyring) Set(item keyring.Item) error {
+	return k.store.Put(context.Background(), item.Key, item.Data, secretstore.Meta{
+		metaLabel:            item.Label,
+		metaDescription:      item.Description,
+		metaModificationTime: time.Now().UTC().Format(time.RFC3339Nano),
+	})
+}
+
+func (k *Keyring) Remove(key string) error {
+	err := k.store.Delete(context.Background(), key)
+	if errors.Is(err, secretstore.ErrNotFound) {
+		return keyring.ErrKeyNotFound
+	}
+
+	return err
+}
+
+func (k *Keyring) Keys() ([]string, error) {
+	return k.store.List(context.Background(), "")
+}
+
+// Close closes KeepassXC client if keyring was created by Open.
+func (k *Keyring) Close() error {
+	if k.client == nil {
+		return nil
+	}
+
+	return k.client.Close()
+}
+
+func (k *Keyring) get(key string) (secretstore.Secret, error) {
+	secret, err := k.store.Get(context.Background(), key)
+	if errors.Is(err, secretstore.ErrNotFound) {
+		return secretstore.Secret{}, keyring.ErrKeyNotFound
+	}
+
+	return secret, err
+}
+
+func itemFromMeta(key string, meta secretstore.Meta) keyring.Item {
+	return keyring.Item{
+		Key:         key,
+		Label:       meta[metaLabel],
+		Description: meta[metaDescription],
+	}
+}
@@ -0,0 +1,137 @@
+package gkpxckeyring_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/99designs/keyring"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/gkpxckeyring"
+	"github.com/xakep666/gkpxc/gkpxctest"
+	"github.com/xakep666/gkpxc/secretstore"
+)
+
+func newKeyring(t *testing.T, srv *gkpxctest.Server) *gkpxckeyring.Keyring {
+	return gkpxckeyring.New(&secretstore.Store{Client: gkpxctest.NewAssociatedClient(t, srv), Namespace: "myapp"})
+}
+
+func TestKeyring(t *testing.T) {
+	srv := gkpxctest.NewTestServer(t)
+	kr := newKeyring(t, srv)
+
+	if _, err := kr.Get("key"); !errors.Is(err, keyring.ErrKeyNotFound) {
+		t.Fatalf("Unexpected error %v, expected ErrKeyNotFound", err)
+	}
+
+	if _, err := kr.GetMetadata("key"); !errors.Is(err, keyring.ErrKeyNotFound) {
+		t.Fatalf("Unexpected error %v, expected ErrKeyNotFound", err)
+	}
+
+	if err := kr.Remove("key"); !errors.Is(err, keyring.ErrKeyNotFound) {
+		t.Fatalf("Unexpected error %v, expected ErrKeyNotFound", err)
+	}
+
+	keys, err := kr.Keys()
+	if err != nil {
+		t.Fatal("Keys", err)
+	}
+
+	if len(keys) != 0 {
+		t.Fatalf("Unexpected keys %v", keys)
+	}
+
+	before := time.Now()
+
+	err = kr.Set(keyring.Item{Key: "key", Data: []byte("secret"), Label: "label", Description: "description"})
+	if err != nil {
+		t.Fatal("Set", err)
+	}
+
+	if err = kr.Set(keyring.Item{Key: "other", Data: []byte("other secret")}); err != nil {
+		t.Fatal("Set", err)
+	}
+
+	groups := srv.Groups()
+	if len(groups.Children) != 1 || groups.Children[0].Name != "gkpxc" ||
+		len(groups.Children[0].Children) != 1 || groups.Children[0].Children[0].Name != "myapp" {
+		t.Fatalf("Unexpected groups %+v", groups)
+	}
+
+	item, err := kr.Get("key")
+	if err != nil {
+		t.Fatal("Get", err)
+	}
+
+	expected := keyring.Item{Key: "key", Data: []byte("secret"), Label: "label", Description: "description"}
+	if !reflect.DeepEqual(item, expected) {
+		t.Fatalf("Unexpected item %+v, expected %+v", item, expected)
+	}
+
+	meta, err := kr.GetMetadata("key")
+	if err != nil {
+		t.Fatal("GetMetadata", err)
+	}
+
+	if meta.Item == nil || meta.Item.Label != "label" || meta.Item.Description != "description" || meta.Item.Data != nil {
+		t.Fatalf("Unexpected metadata item %+v", meta.Item)
+	}
+
+	if meta.ModificationTime.Before(before.Add(-time.Second)) || meta.ModificationTime.After(time.Now()) {
+		t.Fatalf("Unexpected modification time %s", meta.ModificationTime)
+	}
+
+	keys, err = kr.Keys()
+	if err != nil {
+		t.Fatal("Keys", err)
+	}
+
+	if !reflect.DeepEqual(keys, []string{"key", "other"}) {
+		t.Fatalf("Unexpected keys %v", keys)
+	}
+
+	if err = kr.Set(keyring.Item{Key: "key", Data: []byte("new secret")}); err != nil {
+		t.Fatal("Set", err)
+	}
+
+	if item, err = kr.Get("key"); err != nil || string(item.Data) != "new secret" || item.Label != "" {
+		t.Fatalf("Unexpected item %+v after update: %v", item, err)
+	}
+
+	if entries := srv.Entries(); len(entries) != 2 {
+		t.Fatalf("Expected two entries, got %+v", entries)
+	}
+
+	if err = kr.Remove("key"); err != nil {
+		t.Fatal("Remove", err)
+	}
+
+	if _, err = kr.Get("key"); !errors.Is(err, keyring.ErrKeyNotFound) {
+		t.Fatalf("Unexpected error %v, expected ErrKeyNotFound", err)
+	}
+
+	if keys, err = kr.Keys(); err != nil || !reflect.DeepEqual(keys, []string{"other"}) {
+		t.Fatalf("Unexpected keys %v: %v", keys, err)
+	}
+
+	if err = kr.Close(); err != nil {
+		t.Fatal("Close", err)
+	}
+}
+
+func TestKeyring_GroupCreationDenied(t *testing.T) {
+	srv := gkpxctest.NewTestServer(t)
+	srv.SetApprover(func(action string) bool { return action != "create-new-group" })
+
+	kr := newKeyring(t, srv)
+
+	if err := kr.Set(keyring.Item{Key: "key", Data: []byte("secret")}); !gkpxc.IsErrorCode(err, gkpxc.ErrCodeActionCancelledOrDenied) {
+		t.Fatalf("Unexpected error %v, expected action denied", err)
+	}
+
+	if entries := srv.Entries(); len(entries) != 0 {
+		t.Fatalf("Unexpected entries %+v", entries)
+	}
+}
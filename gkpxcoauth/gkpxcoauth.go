@@ -0,0 +1,198 @@
+// Package gkpxcoauth allows to keep OAuth2 tokens in KeepassXC database.
+package gkpxcoauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/xakep666/gkpxc"
+)
+
+// Names of advanced string fields which may hold token parts.
+const (
+	FieldRefreshToken = "refresh_token"
+	FieldExpiry       = "expiry"
+	FieldTokenType    = "token_type"
+)
+
+var (
+	// ErrNoToken returned if store doesn't contain token.
+	ErrNoToken = errors.New("token not found")
+
+	// ErrAmbiguousToken returned if multiple entries found for store URL and login.
+	ErrAmbiguousToken = errors.New("multiple token entries found")
+)
+
+// Store loads and saves token from KeepassXC entry found by synthetic URL.
+//
+// Token may be stored in two ways:
+// 1. Access token in password, refresh token, expiry (RFC3339) and token type in advanced string fields
+// (see FieldRefreshToken, FieldExpiry, FieldTokenType). Such entries may be prepared manually.
+// 2. Whole token encoded as JSON in password.
+// Browser protocol doesn't allow to write advanced string fields, so Store.Save always uses second way.
+type Store struct {
+	Client *gkpxc.Client
+
+	// URL is a synthetic URL used to lookup entry, i.e. "oauth2://github.com/my-cli".
+	URL string
+
+	// Login is an entry login. Used to choose entry among found ones and for new entries.
+	Login string
+
+	// Group and GroupUUID are used to create new entry. Optional.
+	Group, GroupUUID string
+
+	mu        sync.Mutex
+	entryUUID string
+}
+
+// Load loads token from KeepassXC. ErrNoToken returned if no entry found.
+func (s *Store) Load(ctx context.Context) (*oauth2.Token, error) {
+	entry, err := s.lookup(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeToken(entry)
+}
+
+// Save stores token to KeepassXC. Existing entry updated if present.
+func (s *Store) Save(ctx context.Context, token *oauth2.Token) error {
+	s.mu.Lock()
+	entryUUID := s.entryUUID
+	s.mu.Unlock()
+
+	if entryUUID == "" {
+		entry, err := s.lookup(ctx)
+		switch {
+		case errors.Is(err, nil):
+			entryUUID = entry.UUID
+		case errors.Is(err, ErrNoToken):
+		default:
+			return err
+		}
+	}
+
+	serialized, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("serialize token: %w", err)
+	}
+
+	return s.Client.SetLogin(ctx, gkpxc.SetLoginRequest{
+		URL:       s.URL,
+		Login:     s.Login,
+		Password:  string(serialized),
+		Group:     s.Group,
+		GroupUUID: s.GroupUUID,
+		UUID:      entryUUID,
+	})
+}
+
+func (s *Store) lookup(ctx context.Context) (gkpxc.LoginEntry, error) {
+	logins, err := s.Client.GetLogins(ctx, gkpxc.GetLoginsRequest{URL: s.URL})
+	switch {
+	case errors.Is(err, nil):
+	case gkpxc.IsErrorCode(err, gkpxc.ErrCodeNoLoginsFound):
+		return gkpxc.LoginEntry{}, ErrNoToken
+	default:
+		return gkpxc.LoginEntry{}, fmt.Errorf("get logins: %w", err)
+	}
+
+	var found []gkpxc.LoginEntry
+	for _, entry := range logins.Entries {
+		if s.Login == "" || entry.Login == s.Login {
+			found = append(found, entry)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return gkpxc.LoginEntry{}, ErrNoToken
+	case 1:
+		s.mu.Lock()
+		s.entryUUID = found[0].UUID
+		s.mu.Unlock()
+
+		return found[0], nil
+	default:
+		return gkpxc.LoginEntry{}, ErrAmbiguousToken
+	}
+}
+
+func decodeToken(entry gkpxc.LoginEntry) (*oauth2.Token, error) {
+	var token oauth2.Token
+	if err := json.Unmarshal([]byte(entry.Password), &token); err == nil && token.AccessToken != "" {
+		return &token, nil
+	}
+
+	token = oauth2.Token{AccessToken: entry.Password}
+	token.RefreshToken, _ = entry.StringField(FieldRefreshToken)
+	token.TokenType, _ = entry.StringField(FieldTokenType)
+
+	if expiry, ok := entry.StringField(FieldExpiry); ok && expiry != "" {
+		var err error
+		if token.Expiry, err = time.Parse(time.RFC3339, expiry); err != nil {
+			return nil, fmt.Errorf("parse expiry: %w", err)
+		}
+	}
+
+	return &token, nil
+}
+
+// TokenSource returns token source which takes initial token from store, refreshes it using config
+// and saves refreshed tokens back to store. Context is used for refresh and store requests.
+func TokenSource(ctx context.Context, config *oauth2.Config, store *Store) (oauth2.TokenSource, error) {
+	token, err := store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPersistingTokenSource(ctx, store, token, config.TokenSource(ctx, token)), nil
+}
+
+// NewPersistingTokenSource wraps token source to save every new token (compared with last one) to store.
+func NewPersistingTokenSource(ctx context.Context, store *Store, last *oauth2.Token, src oauth2.TokenSource) oauth2.TokenSource {
+	return &persistingTokenSource{
+		ctx:   ctx,
+		store: store,
+		src:   src,
+		last:  last,
+	}
+}
+
+type persistingTokenSource struct {
+	ctx   context.Context
+	store *Store
+	src   oauth2.TokenSource
+
+	mu   sync.Mutex
+	last *oauth2.Token
+}
+
+func (p *persistingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := p.src.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.last != nil && p.last.AccessToken == token.AccessToken && p.last.RefreshToken == token.RefreshToken {
+		return token, nil
+	}
+
+	if err = p.store.Save(p.ctx, token); err != nil {
+		return nil, fmt.Errorf("save token: %w", err)
+	}
+
+	p.last = token
+
+	return token, nil
+}
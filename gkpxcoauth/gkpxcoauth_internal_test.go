@@ -0,0 +1,52 @@
+package gkpxcoauth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xakep666/gkpxc"
+)
+
+func TestDecodeToken(t *testing.T) {
+	expiry := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("json", func(t *testing.T) {
+		token, err := decodeToken(gkpxc.LoginEntry{
+			Password: `{"access_token":"access","token_type":"Bearer","refresh_token":"refresh","expiry":"2022-06-01T10:00:00Z"}`,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+
+		if token.AccessToken != "access" || token.RefreshToken != "refresh" || token.TokenType != "Bearer" || !token.Expiry.Equal(expiry) {
+			t.Fatalf("Unexpected token %+v", token)
+		}
+	})
+
+	t.Run("string fields", func(t *testing.T) {
+		token, err := decodeToken(gkpxc.LoginEntry{
+			Password: "access",
+			StringFields: []map[string]string{
+				{"KPH: refresh_token": "refresh"},
+				{"KPH: expiry": "2022-06-01T10:00:00Z"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+
+		if token.AccessToken != "access" || token.RefreshToken != "refresh" || !token.Expiry.Equal(expiry) {
+			t.Fatalf("Unexpected token %+v", token)
+		}
+	})
+
+	t.Run("invalid expiry", func(t *testing.T) {
+		_, err := decodeToken(gkpxc.LoginEntry{
+			Password:     "access",
+			StringFields: []map[string]string{{"KPH: expiry": "tomorrow"}},
+		})
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package gkpxcoauth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/xakep666/gkpxc/gkpxcoauth"
+	"github.com/xakep666/gkpxc/gkpxctest"
+)
+
+const storeURL = "oauth2://github.com/my-cli"
+
+type tokenSourceFunc func() (*oauth2.Token, error)
+
+func (f tokenSourceFunc) Token() (*oauth2.Token, error) { return f() }
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	srv := gkpxctest.NewTestServer(t)
+	store := &gkpxcoauth.Store{Client: gkpxctest.NewAssociatedClient(t, srv), URL: storeURL, Login: "user"}
+
+	if _, err := store.Load(ctx); !errors.Is(err, gkpxcoauth.ErrNoToken) {
+		t.Fatalf("Unexpected error %v, expected ErrNoToken", err)
+	}
+
+	// entry of another user must be ignored
+	srv.AddEntry(gkpxctest.Entry{URL: storeURL, Login: "other", Password: "other-token"})
+
+	if _, err := store.Load(ctx); !errors.Is(err, gkpxcoauth.ErrNoToken) {
+		t.Fatalf("Unexpected error %v, expected ErrNoToken", err)
+	}
+
+	token := &oauth2.Token{
+		AccessToken:  "access",
+		TokenType:    "Bearer",
+		RefreshToken: "refresh",
+		Expiry:       time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	if err := store.Save(ctx, token); err != nil {
+		t.Fatal("Save", err)
+	}
+
+	loaded, err := store.Load(ctx)
+	if err != nil {
+		t.Fatal("Load", err)
+	}
+
+	if loaded.AccessToken != token.AccessToken || loaded.RefreshToken != token.RefreshToken ||
+		loaded.TokenType != token.TokenType || !loaded.Expiry.Equal(token.Expiry) {
+		t.Fatalf("Unexpected token %+v, expected %+v", loaded, token)
+	}
+
+	// new store instance must find and update existing entry
+	store = &gkpxcoauth.Store{Client: gkpxctest.NewAssociatedClient(t, srv), URL: storeURL, Login: "user"}
+	if err = store.Save(ctx, &oauth2.Token{AccessToken: "access2"}); err != nil {
+		t.Fatal("Save", err)
+	}
+
+	if entries := srv.Entries(); len(entries) != 2 {
+		t.Fatalf("Expected two entries, got %+v", entries)
+	}
+
+	if loaded, err = store.Load(ctx); err != nil || loaded.AccessToken != "access2" {
+		t.Fatalf("Unexpected token %+v: %v", loaded, err)
+	}
+}
+
+func TestStore_StringFields(t *testing.T) {
+	srv := gkpxctest.NewTestServer(t)
+	srv.AddEntry(gkpxctest.Entry{
+		URL:      storeURL,
+		Login:    "user",
+		Password: "access",
+		StringFields: map[string]string{
+			gkpxcoauth.FieldRefreshToken: "refresh",
+			gkpxcoauth.FieldExpiry:       "2022-06-01T10:00:00Z",
+		},
+	})
+
+	store := &gkpxcoauth.Store{Client: gkpxctest.NewAssociatedClient(t, srv), URL: storeURL, Login: "user"}
+
+	token, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatal("Load", err)
+	}
+
+	expiry := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	if token.AccessToken != "access" || token.RefreshToken != "refresh" || !token.Expiry.Equal(expiry) {
+		t.Fatalf("Unexpected token %+v", token)
+	}
+}
+
+func TestStore_Ambiguous(t *testing.T) {
+	srv := gkpxctest.NewTestServer(t)
+	srv.AddEntry(gkpxctest.Entry{URL: storeURL, Login: "user1", Password: "access1"})
+	srv.AddEntry(gkpxctest.Entry{URL: storeURL, Login: "user2", Password: "access2"})
+
+	store := &gkpxcoauth.Store{Client: gkpxctest.NewAssociatedClient(t, srv), URL: storeURL}
+
+	if _, err := store.Load(context.Background()); !errors.Is(err, gkpxcoauth.ErrAmbiguousToken) {
+		t.Fatalf("Unexpected error %v, expected ErrAmbiguousToken", err)
+	}
+}
+
+func TestPersistingTokenSource(t *testing.T) {
+	ctx := context.Background()
+	srv := gkpxctest.NewTestServer(t)
+	uuid := srv.AddEntry(gkpxctest.Entry{URL: storeURL, Login: "user", Password: `{"access_token":"access1"}`})
+
+	store := &gkpxcoauth.Store{Client: gkpxctest.NewAssociatedClient(t, srv), URL: storeURL, Login: "user"}
+
+	initial, err := store.Load(ctx)
+	if err != nil {
+		t.Fatal("Load", err)
+	}
+
+	next := &oauth2.Token{AccessToken: "access1"}
+	src := gkpxcoauth.NewPersistingTokenSource(ctx, store, initial, tokenSourceFunc(func() (*oauth2.Token, error) {
+		return next, nil
+	}))
+
+	if _, err = src.Token(); err != nil {
+		t.Fatal("Token", err)
+	}
+
+	if entries := srv.Entries(); entries[0].Password != `{"access_token":"access1"}` {
+		t.Fatalf("Unchanged token must not be saved, got entry %+v", entries[0])
+	}
+
+	next = &oauth2.Token{AccessToken: "access2", RefreshToken: "refresh2"}
+
+	token, err := src.Token()
+	if err != nil {
+		t.Fatal("Token", err)
+	}
+
+	if token.AccessToken != "access2" {
+		t.Fatalf("Unexpected token %+v", token)
+	}
+
+	entries := srv.Entries()
+	if len(entries) != 1 || entries[0].UUID != uuid {
+		t.Fatalf("Refreshed token must be saved to existing entry %s, got %+v", uuid, entries)
+	}
+
+	stored, err := store.Load(ctx)
+	if err != nil {
+		t.Fatal("Load", err)
+	}
+
+	if stored.AccessToken != "access2" || stored.RefreshToken != "refresh2" {
+		t.Fatalf("Unexpected stored token %+v", stored)
+	}
+}
@@ -0,0 +1,78 @@
+// Package gkpxcotel provides OpenTelemetry instrumentation for gkpxc.Client.
+package gkpxcotel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/xakep666/gkpxc"
+)
+
+const instrumentationName = "github.com/xakep666/gkpxc"
+
+// Span attributes.
+const (
+	ActionKey       = attribute.Key("keepassxc.action")
+	RequestSizeKey  = attribute.Key("keepassxc.request.size")
+	ResponseSizeKey = attribute.Key("keepassxc.response.size")
+	ErrorClassKey   = attribute.Key("keepassxc.error.class")
+	ErrorCodeKey    = attribute.Key("keepassxc.error.code")
+)
+
+// Observer creates span per KeepassXC exchange. OpenTelemetry metrics API is not stable yet so metrics are not provided,
+// use LockStateHandler to track database lock state.
+type Observer struct {
+	tracer trace.Tracer
+
+	// LockStateHandler is called on lock state change, i.e. to update gauge.
+	LockStateHandler func(locked bool)
+}
+
+// NewObserver creates observer using tracer provider. If tp is nil global provider used.
+func NewObserver(tp trace.TracerProvider) *Observer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+
+	return &Observer{tracer: tp.Tracer(instrumentationName)}
+}
+
+// ClientOption returns option to use observer in client.
+func (o *Observer) ClientOption() gkpxc.ClientOption { return gkpxc.WithObserver(o) }
+
+// StartAction starts span named "keepassxc <action>".
+func (o *Observer) StartAction(ctx context.Context, action string) func(gkpxc.ActionStats) {
+	_, span := o.tracer.Start(ctx, "keepassxc "+action,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(ActionKey.String(action)),
+	)
+
+	return func(stats gkpxc.ActionStats) {
+		span.SetAttributes(
+			RequestSizeKey.Int(stats.RequestBytes),
+			ResponseSizeKey.Int(stats.ResponseBytes),
+		)
+
+		if stats.ErrorClass != gkpxc.ErrorClassNone {
+			span.SetAttributes(ErrorClassKey.String(string(stats.ErrorClass)))
+			span.SetStatus(codes.Error, string(stats.ErrorClass))
+		}
+
+		if stats.ErrorCode != 0 {
+			span.SetAttributes(ErrorCodeKey.Int(stats.ErrorCode))
+		}
+
+		span.End(trace.WithTimestamp(stats.Start.Add(stats.Duration)))
+	}
+}
+
+// LockStateChanged passes lock state to LockStateHandler.
+func (o *Observer) LockStateChanged(locked bool) {
+	if o.LockStateHandler != nil {
+		o.LockStateHandler(locked)
+	}
+}
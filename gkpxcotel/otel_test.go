@@ -0,0 +1,65 @@
+package gkpxcotel_test
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/gkpxcotel"
+	"github.com/xakep666/gkpxc/gkpxctest"
+)
+
+func TestObserver(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	observer := gkpxcotel.NewObserver(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx := context.Background()
+
+	client, err := gkpxc.NewClient(ctx, gkpxc.WithConn(srv.Dial()), observer.ClientOption())
+	if err != nil {
+		t.Fatal("NewClient", err)
+	}
+
+	defer client.Close()
+
+	if err = client.Associate(ctx); err != nil {
+		t.Fatal("Associate", err)
+	}
+
+	_, err = client.GetLogins(ctx, gkpxc.GetLoginsRequest{URL: "https://example.com"})
+	if !gkpxc.IsErrorCode(err, gkpxc.ErrCodeNoLoginsFound) {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("Got %d spans, expected 4", len(spans))
+	}
+
+	associate, getLogins := spans[1], spans[3]
+	if associate.Name() != "keepassxc associate" || associate.Status().Code == codes.Error {
+		t.Fatalf("Unexpected span %s: %+v", associate.Name(), associate.Status())
+	}
+
+	if getLogins.Name() != "keepassxc get-logins" || getLogins.Status().Code != codes.Error {
+		t.Fatalf("Unexpected span %s: %+v", getLogins.Name(), getLogins.Status())
+	}
+
+	attrs := make(map[string]interface{})
+	for _, attr := range getLogins.Attributes() {
+		attrs[string(attr.Key)] = attr.Value.AsInterface()
+	}
+
+	if attrs[string(gkpxcotel.ErrorCodeKey)] != int64(gkpxc.ErrCodeNoLoginsFound) ||
+		attrs[string(gkpxcotel.ErrorClassKey)] != string(gkpxc.ErrorClassKeepassXC) ||
+		attrs[string(gkpxcotel.ActionKey)] != "get-logins" {
+		t.Fatalf("Unexpected attributes %v", attrs)
+	}
+}
@@ -0,0 +1,236 @@
+package gkpxctest
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/xakep666/gkpxc"
+)
+
+type response = map[string]interface{}
+
+func (s *Server) dispatch(action string, triggerUnlock bool, payload map[string]json.RawMessage) (response, *gkpxc.ErrorResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.locked {
+		if !triggerUnlock || !s.unlockable {
+			return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeDatabaseNotOpened, Text: "Database not opened"}
+		}
+
+		s.locked = false
+	}
+
+	switch action {
+	case "get-databasehash":
+		return response{"hash": s.hash}, nil
+	case "associate":
+		return s.associate(payload)
+	case "test-associate":
+		return s.testAssociate(payload)
+	case "get-logins":
+		return s.getLogins(payload)
+	case "set-login":
+		return s.setLogin(payload)
+	case "delete-entry":
+		return s.deleteEntry(payload)
+	case "get-database-groups":
+		return response{"defaultGroup": "", "defaultGroupAlwaysAllow": false, "groups": response{"groups": []gkpxc.DatabaseGroup{s.root}}}, nil
+	case "create-new-group":
+		return s.createNewGroup(payload)
+	case "get-totp":
+		return s.getTOTP(payload)
+	case "lock-database":
+		s.locked = true
+		return response{}, nil
+	case "request-autotype":
+		return response{}, nil
+	default:
+		return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeIncorrectAction, Text: "Incorrect action"}
+	}
+}
+
+func (s *Server) associate(payload map[string]json.RawMessage) (response, *gkpxc.ErrorResponse) {
+	var req gkpxc.AssociateRequest
+	if err := unmarshalPayload(payload, &req); err != nil || len(req.IDKey) != gkpxc.KeySize {
+		return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeAssociationFailed, Text: "Association failed"}
+	}
+
+	if !s.approved("associate") {
+		return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeActionCancelledOrDenied, Text: "Action cancelled or denied"}
+	}
+
+	id := "gkpxctest-" + randomHex(4)
+	s.associations[id] = req.IDKey
+
+	return response{"id": id, "hash": s.hash}, nil
+}
+
+func (s *Server) testAssociate(payload map[string]json.RawMessage) (response, *gkpxc.ErrorResponse) {
+	var req gkpxc.TestAssociateRequest
+	if err := unmarshalPayload(payload, &req); err != nil || !s.associated(req.ID, req.Key) {
+		return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeAssociationFailed, Text: "KeePassXC association failed, try again"}
+	}
+
+	return response{"id": req.ID, "hash": s.hash}, nil
+}
+
+func (s *Server) associated(id string, key []byte) bool {
+	known, ok := s.associations[id]
+	return ok && bytes.Equal(known, key)
+}
+
+func (s *Server) getLogins(payload map[string]json.RawMessage) (response, *gkpxc.ErrorResponse) {
+	var req gkpxc.GetLoginsRequest
+	if err := unmarshalPayload(payload, &req); err != nil || req.URL == "" {
+		return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeNoURLProvided, Text: "No URL provided"}
+	}
+
+	authorized := false
+	for _, key := range req.Keys {
+		authorized = authorized || s.associated(key.ID, key.Key)
+	}
+
+	if !authorized {
+		return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeAssociationFailed, Text: "KeePassXC association failed, try again"}
+	}
+
+	var entries []gkpxc.LoginEntry
+	for _, entry := range s.entries {
+		if !matchURL(entry.URL, req.URL) {
+			continue
+		}
+
+		loginEntry := gkpxc.LoginEntry{
+			UUID:     entry.UUID,
+			Name:     entry.Name,
+			Login:    entry.Login,
+			Password: entry.Password,
+			TOTP:     entry.TOTP,
+			Expired:  entry.Expired,
+		}
+
+		for name, value := range entry.StringFields {
+			loginEntry.StringFields = append(loginEntry.StringFields, map[string]string{gkpxc.StringFieldPrefix + name: value})
+		}
+
+		entries = append(entries, loginEntry)
+	}
+
+	if len(entries) == 0 {
+		return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeNoLoginsFound, Text: "No logins found"}
+	}
+
+	return response{"count": len(entries), "entries": entries}, nil
+}
+
+func (s *Server) setLogin(payload map[string]json.RawMessage) (response, *gkpxc.ErrorResponse) {
+	var req gkpxc.SetLoginRequest
+	if err := unmarshalPayload(payload, &req); err != nil || req.URL == "" {
+		return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeNoURLProvided, Text: "No URL provided"}
+	}
+
+	groupUUID := req.GroupUUID
+	if groupUUID == "" {
+		groupUUID = s.root.UUID
+	}
+
+	if req.UUID != "" {
+		for i := range s.entries {
+			if s.entries[i].UUID == req.UUID {
+				s.entries[i].URL = req.URL
+				s.entries[i].Login = req.Login
+				s.entries[i].Password = req.Password
+
+				return response{"count": nil, "entries": nil, "error": ""}, nil
+			}
+		}
+	}
+
+	s.entries = append(s.entries, Entry{
+		UUID:      randomHex(16),
+		Name:      hostOf(req.URL),
+		URL:       req.URL,
+		Login:     req.Login,
+		Password:  req.Password,
+		GroupUUID: groupUUID,
+	})
+
+	return response{"count": nil, "entries": nil, "error": ""}, nil
+}
+
+func (s *Server) deleteEntry(payload map[string]json.RawMessage) (response, *gkpxc.ErrorResponse) {
+	var req gkpxc.DeleteEntryRequest
+	if err := unmarshalPayload(payload, &req); err != nil || req.UUID == "" {
+		return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeNoValidUUIDProvided, Text: "No valid UUID provided"}
+	}
+
+	for i, entry := range s.entries {
+		if entry.UUID != req.UUID {
+			continue
+		}
+
+		if !s.approved("delete-entry") {
+			return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeActionCancelledOrDenied, Text: "Action cancelled or denied"}
+		}
+
+		s.entries = append(s.entries[:i], s.entries[i+1:]...)
+
+		return response{}, nil
+	}
+
+	return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeNoValidUUIDProvided, Text: "No valid UUID provided"}
+}
+
+func (s *Server) createNewGroup(payload map[string]json.RawMessage) (response, *gkpxc.ErrorResponse) {
+	var req gkpxc.CreateNewGroupRequest
+	if err := unmarshalPayload(payload, &req); err != nil || strings.Trim(req.Name, "/") == "" {
+		return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeCannotCreateNewGroup, Text: "Cannot create new group"}
+	}
+
+	current := &s.root
+	created := false
+
+path:
+	for _, name := range strings.Split(strings.Trim(req.Name, "/"), "/") {
+		for i := range current.Children {
+			if current.Children[i].Name == name {
+				current = &current.Children[i]
+				continue path
+			}
+		}
+
+		if !created && !s.approved("create-new-group") {
+			return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeActionCancelledOrDenied, Text: "Action cancelled or denied"}
+		}
+
+		created = true
+		current.Children = append(current.Children, gkpxc.DatabaseGroup{Name: name, UUID: randomHex(16)})
+		current = &current.Children[len(current.Children)-1]
+	}
+
+	return response{"name": current.Name, "uuid": current.UUID}, nil
+}
+
+func (s *Server) getTOTP(payload map[string]json.RawMessage) (response, *gkpxc.ErrorResponse) {
+	var req gkpxc.GetTOTPRequest
+	if err := unmarshalPayload(payload, &req); err == nil {
+		for _, entry := range s.entries {
+			if entry.UUID == req.UUID {
+				return response{"totp": entry.TOTP}, nil
+			}
+		}
+	}
+
+	return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeNoValidUUIDProvided, Text: "No valid UUID provided"}
+}
+
+func unmarshalPayload(payload map[string]json.RawMessage, to interface{}) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, to)
+}
@@ -0,0 +1,269 @@
+package gkpxctest
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/xakep666/gkpxc"
+)
+
+// Message sources in Transcript.
+const (
+	FromClient = "client"
+	FromServer = "server"
+)
+
+// Transcript is a recorded session with KeepassXC.
+type Transcript struct {
+	// ServerPublicKey and ServerPrivateKey are keys presented to client as KeepassXC ones during handshake.
+	// Replay uses them to encrypt recorded responses.
+	ServerPublicKey  []byte `json:"serverPublicKey"`
+	ServerPrivateKey []byte `json:"serverPrivateKey"`
+
+	Steps []Step `json:"steps"`
+}
+
+// Step is a single message sent by client or server.
+type Step struct {
+	// From is FromClient or FromServer.
+	From string `json:"from"`
+
+	// Message is a message envelope. Encrypted payload is moved to Payload.
+	Message gkpxc.Message `json:"message"`
+
+	// Payload is a plaintext of encrypted message.
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// LoadTranscript reads transcript from file.
+func LoadTranscript(path string) (Transcript, error) {
+	var t Transcript
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Transcript{}, err
+	}
+
+	if err = json.Unmarshal(content, &t); err != nil {
+		return Transcript{}, fmt.Errorf("parse transcript %s: %w", path, err)
+	}
+
+	return t, nil
+}
+
+// Save writes transcript to file. File contains association keys and database content so it's written with
+// owner-only permissions. Use test database to record sessions.
+func (t Transcript) Save(path string) error {
+	content, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(content, '\n'), 0o600)
+}
+
+// Recorder records session between client and KeepassXC. It works as man-in-the-middle: client and KeepassXC
+// exchange public keys with recorder so it's able to decrypt messages.
+//
+//	conn, _ := net.Dial("unix", socketPath)
+//	rec, _ := gkpxctest.NewRecorder(conn)
+//	client, _ := gkpxc.NewClient(ctx, gkpxc.WithConn(rec.Conn()))
+//	// use client
+//	client.Close()
+//	rec.Close()
+//	rec.Transcript().Save("testdata/session.json")
+type Recorder struct {
+	upstream   net.Conn
+	conn, peer net.Conn
+
+	upPub, upPriv     *[gkpxc.KeySize]byte // keys presented to KeepassXC
+	downPub, downPriv *[gkpxc.KeySize]byte // keys presented to client
+
+	mu                   sync.Mutex
+	upShared, downShared *[gkpxc.KeySize]byte
+	transcript           Transcript
+	err                  error
+
+	wg sync.WaitGroup
+}
+
+// NewRecorder creates recorder of session with KeepassXC connected with upstream.
+func NewRecorder(upstream net.Conn) (*Recorder, error) {
+	upPub, upPriv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate keypair: %w", err)
+	}
+
+	downPub, downPriv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate keypair: %w", err)
+	}
+
+	conn, peer := net.Pipe()
+
+	r := &Recorder{
+		upstream: upstream,
+		conn:     conn,
+		peer:     peer,
+		upPub:    upPub,
+		upPriv:   upPriv,
+		downPub:  downPub,
+		downPriv: downPriv,
+		transcript: Transcript{
+			ServerPublicKey:  (*downPub)[:],
+			ServerPrivateKey: (*downPriv)[:],
+		},
+	}
+
+	r.wg.Add(2)
+	go r.forward(peer, upstream, r.clientMessage)
+	go r.forward(upstream, peer, r.serverMessage)
+
+	return r, nil
+}
+
+// Conn returns connection for client. Use it with gkpxc.WithConn.
+func (r *Recorder) Conn() net.Conn { return r.conn }
+
+// Close closes connections and waits for recording finish. Recording error returned if any.
+func (r *Recorder) Close() error {
+	r.conn.Close()
+	r.peer.Close()
+	r.upstream.Close()
+	r.wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Transcript returns recorded session.
+func (r *Recorder) Transcript() Transcript {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := r.transcript
+	t.Steps = append([]Step(nil), t.Steps...)
+
+	return t
+}
+
+func (r *Recorder) forward(from, to net.Conn, handle func(msg gkpxc.Message) (gkpxc.Message, error)) {
+	defer r.wg.Done()
+	defer to.Close()
+
+	dec := json.NewDecoder(from)
+	enc := json.NewEncoder(to)
+
+	for {
+		var msg gkpxc.Message
+		if err := dec.Decode(&msg); err != nil {
+			return
+		}
+
+		msg, err := handle(msg)
+		if err == nil {
+			err = enc.Encode(msg)
+		}
+
+		if err != nil && !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.ErrClosedPipe) {
+			r.mu.Lock()
+			if r.err == nil {
+				r.err = err
+			}
+			r.mu.Unlock()
+
+			return
+		}
+	}
+}
+
+func (r *Recorder) clientMessage(msg gkpxc.Message) (gkpxc.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	step := Step{From: FromClient, Message: msg}
+
+	switch {
+	case msg.Action == "change-public-keys":
+		if len(msg.PublicKey) != gkpxc.KeySize {
+			return gkpxc.Message{}, fmt.Errorf("invalid client public key")
+		}
+
+		r.downShared = new([gkpxc.KeySize]byte)
+		box.Precompute(r.downShared, (*[gkpxc.KeySize]byte)(msg.PublicKey), r.downPriv)
+		msg.PublicKey = (*r.upPub)[:]
+	case len(msg.Message) > 0:
+		plain, err := openMessage(msg, r.downShared, r.upShared)
+		if err != nil {
+			return gkpxc.Message{}, fmt.Errorf("client message %s: %w", msg.Action, err)
+		}
+
+		step.Message.Message, step.Payload = nil, plain
+		msg.Message = box.SealAfterPrecomputation(nil, plain, (*[gkpxc.NonceSize]byte)(msg.Nonce), r.upShared)
+	}
+
+	r.transcript.Steps = append(r.transcript.Steps, step)
+
+	return msg, nil
+}
+
+func (r *Recorder) serverMessage(msg gkpxc.Message) (gkpxc.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case msg.Action == "change-public-keys" && len(msg.PublicKey) > 0:
+		if len(msg.PublicKey) != gkpxc.KeySize {
+			return gkpxc.Message{}, fmt.Errorf("invalid server public key")
+		}
+
+		r.upShared = new([gkpxc.KeySize]byte)
+		box.Precompute(r.upShared, (*[gkpxc.KeySize]byte)(msg.PublicKey), r.upPriv)
+		msg.PublicKey = (*r.downPub)[:]
+	case len(msg.Message) > 0:
+		plain, err := openMessage(msg, r.upShared, r.downShared)
+		if err != nil {
+			return gkpxc.Message{}, fmt.Errorf("server message %s: %w", msg.Action, err)
+		}
+
+		step := Step{From: FromServer, Message: msg, Payload: plain}
+		step.Message.Message = nil
+		r.transcript.Steps = append(r.transcript.Steps, step)
+
+		msg.Message = box.SealAfterPrecomputation(nil, plain, (*[gkpxc.NonceSize]byte)(msg.Nonce), r.downShared)
+
+		return msg, nil
+	}
+
+	r.transcript.Steps = append(r.transcript.Steps, Step{From: FromServer, Message: msg})
+
+	return msg, nil
+}
+
+// openMessage decrypts message with openKey. sealKey checked to ensure that message can be encrypted for other side.
+func openMessage(msg gkpxc.Message, openKey, sealKey *[gkpxc.KeySize]byte) ([]byte, error) {
+	if openKey == nil || sealKey == nil {
+		return nil, fmt.Errorf("public keys not exchanged")
+	}
+
+	if len(msg.Nonce) != gkpxc.NonceSize {
+		return nil, fmt.Errorf("invalid nonce")
+	}
+
+	plain, ok := box.OpenAfterPrecomputation(nil, msg.Message, (*[gkpxc.NonceSize]byte)(msg.Nonce), openKey)
+	if !ok {
+		return nil, gkpxc.ErrDecryptFailed
+	}
+
+	return plain, nil
+}
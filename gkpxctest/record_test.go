@@ -0,0 +1,289 @@
+package gkpxctest_test
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/gkpxctest"
+)
+
+var update = flag.Bool("update", false, "record transcripts in testdata again")
+
+type sessionResult struct {
+	Hash   string
+	Logins []gkpxc.LoginEntry
+	Locked bool
+	Err    error
+}
+
+// session does same actions on recording and replay.
+func session(t *testing.T, conn func() (*gkpxc.Client, <-chan bool)) sessionResult {
+	ctx := context.Background()
+
+	client, locks := conn()
+	defer client.Close()
+
+	var res sessionResult
+
+	hash, err := client.GetDatabaseHash(ctx, false)
+	if err != nil {
+		t.Fatal("GetDatabaseHash", err)
+	}
+
+	res.Hash = hash.Hash
+
+	if err = client.Associate(ctx); err != nil {
+		t.Fatal("Associate", err)
+	}
+
+	err = client.SetLogin(ctx, gkpxc.SetLoginRequest{URL: "https://example.com", Login: "user", Password: "pass"})
+	if err != nil {
+		t.Fatal("SetLogin", err)
+	}
+
+	logins, err := client.GetLogins(ctx, gkpxc.GetLoginsRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatal("GetLogins", err)
+	}
+
+	res.Logins = logins.Entries
+
+	_, res.Err = client.GetLogins(ctx, gkpxc.GetLoginsRequest{URL: "https://other.com"})
+
+	if err = client.LockDatabase(ctx); err != nil {
+		t.Fatal("LockDatabase", err)
+	}
+
+	select {
+	case res.Locked = <-locks:
+	case <-time.After(time.Second):
+		t.Fatal("Lock signal not received")
+	}
+
+	return res
+}
+
+func TestRecorder(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	rec, err := gkpxctest.NewRecorder(srv.Dial())
+	if err != nil {
+		t.Fatal("NewRecorder", err)
+	}
+
+	recorded := session(t, func() (*gkpxc.Client, <-chan bool) {
+		locks := make(chan bool, 1)
+
+		client, err := gkpxc.NewClient(context.Background(), gkpxc.WithConn(rec.Conn()),
+			gkpxc.WithLockChangeHandler(func(locked bool) { locks <- locked }))
+		if err != nil {
+			t.Fatal("NewClient", err)
+		}
+
+		return client, locks
+	})
+
+	if err = rec.Close(); err != nil {
+		t.Fatal("Recorder", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err = rec.Transcript().Save(path); err != nil {
+		t.Fatal("Save", err)
+	}
+
+	transcript, err := gkpxctest.LoadTranscript(path)
+	if err != nil {
+		t.Fatal("LoadTranscript", err)
+	}
+
+	for _, step := range transcript.Steps {
+		if len(step.Message.Message) > 0 {
+			t.Fatalf("Encrypted message recorded: %+v", step)
+		}
+	}
+
+	replay := gkpxctest.NewReplayServer(transcript)
+	defer replay.Close()
+
+	replayed := session(t, func() (*gkpxc.Client, <-chan bool) {
+		locks := make(chan bool, 1)
+
+		client, err := gkpxc.NewClient(context.Background(), gkpxc.WithConn(replay.Dial()),
+			gkpxc.WithLockChangeHandler(func(locked bool) { locks <- locked }))
+		if err != nil {
+			t.Fatal("NewClient", err)
+		}
+
+		return client, locks
+	})
+
+	if !reflect.DeepEqual(recorded, replayed) {
+		t.Fatalf("Replayed %+v, recorded %+v", replayed, recorded)
+	}
+
+	if !gkpxc.IsErrorCode(replayed.Err, gkpxc.ErrCodeNoLoginsFound) || !replayed.Locked || len(replayed.Logins) != 1 {
+		t.Fatalf("Unexpected session result %+v", replayed)
+	}
+
+	if err = replay.Err(); err != nil {
+		t.Fatal("Replay", err)
+	}
+}
+
+func TestReplayServer_Unexpected_action(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	rec, err := gkpxctest.NewRecorder(srv.Dial())
+	if err != nil {
+		t.Fatal("NewRecorder", err)
+	}
+
+	ctx := context.Background()
+
+	client, err := gkpxc.NewClient(ctx, gkpxc.WithConn(rec.Conn()))
+	if err != nil {
+		t.Fatal("NewClient", err)
+	}
+
+	if _, err = client.GetDatabaseHash(ctx, false); err != nil {
+		t.Fatal("GetDatabaseHash", err)
+	}
+
+	client.Close()
+	rec.Close()
+
+	replay := gkpxctest.NewReplayServer(rec.Transcript())
+	defer replay.Close()
+
+	client, err = gkpxc.NewClient(ctx, gkpxc.WithConn(replay.Dial()))
+	if err != nil {
+		t.Fatal("NewClient", err)
+	}
+
+	defer client.Close()
+
+	if err = client.Associate(ctx); !gkpxc.IsErrorCode(err, gkpxc.ErrCodeIncorrectAction) {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if err = replay.Err(); err == nil || !strings.Contains(err.Error(), "unexpected action associate") {
+		t.Fatalf("Unexpected replay error %v", err)
+	}
+}
+
+// notifyConn closes written after first write containing marker, i.e. when request reached server over pipe.
+type notifyConn struct {
+	net.Conn
+	marker  []byte
+	written chan struct{}
+	once    sync.Once
+}
+
+func (c *notifyConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if bytes.Contains(p, c.marker) {
+		c.once.Do(func() { close(c.written) })
+	}
+
+	return n, err
+}
+
+// generateSession requests password and database hash while generator dialog is shown.
+// Recording session releases generator with release after hash received.
+func generateSession(t *testing.T, conn net.Conn, release func()) (hash, password string) {
+	ctx := context.Background()
+
+	nc := &notifyConn{Conn: conn, marker: []byte(`"action":"generate-password"`), written: make(chan struct{})}
+
+	client, err := gkpxc.NewClient(ctx, gkpxc.WithConn(nc))
+	if err != nil {
+		t.Fatal("NewClient", err)
+	}
+
+	defer client.Close()
+
+	if err = client.Associate(ctx); err != nil {
+		t.Fatal("Associate", err)
+	}
+
+	generated := make(chan error, 1)
+
+	go func() {
+		var err error
+		password, err = client.GeneratePassword(ctx)
+		generated <- err
+	}()
+
+	<-nc.written
+
+	resp, err := client.GetDatabaseHash(ctx, false)
+	if err != nil {
+		t.Fatal("GetDatabaseHash", err)
+	}
+
+	if release != nil {
+		release()
+	}
+
+	if err = <-generated; err != nil {
+		t.Fatal("GeneratePassword", err)
+	}
+
+	return resp.Hash, password
+}
+
+func TestReplayServer_GeneratePassword(t *testing.T) {
+	path := filepath.Join("testdata", "generate-password.json")
+
+	if *update {
+		srv := gkpxctest.NewTestServer(t)
+
+		answers := make(chan string)
+		srv.SetPasswordGenerator(func() (string, bool) { return <-answers, true })
+
+		rec, err := gkpxctest.NewRecorder(srv.Dial())
+		if err != nil {
+			t.Fatal("NewRecorder", err)
+		}
+
+		generateSession(t, rec.Conn(), func() { answers <- "generated" })
+
+		if err = rec.Close(); err != nil {
+			t.Fatal("Recorder", err)
+		}
+
+		if err = rec.Transcript().Save(path); err != nil {
+			t.Fatal("Save", err)
+		}
+	}
+
+	transcript, err := gkpxctest.LoadTranscript(path)
+	if err != nil {
+		t.Fatal("LoadTranscript", err)
+	}
+
+	replay := gkpxctest.NewReplayServer(transcript)
+	defer replay.Close()
+
+	// async response is recorded after response to following request
+	hash, password := generateSession(t, replay.Dial(), nil)
+	if hash == "" || password != "generated" {
+		t.Fatalf("Unexpected hash %q and password %q", hash, password)
+	}
+
+	if err = replay.Err(); err != nil {
+		t.Fatal("Replay", err)
+	}
+}
@@ -0,0 +1,234 @@
+package gkpxctest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/xakep666/gkpxc"
+)
+
+// ReplayServer plays recorded Transcript back. It expects client messages with same actions in same order
+// and responds with recorded messages encrypted with keys from transcript. Request payloads are not compared
+// because they contain random keys.
+type ReplayServer struct {
+	transcript Transcript
+
+	mu    sync.Mutex
+	err   error
+	conns []net.Conn
+}
+
+// NewReplayServer creates server replaying transcript.
+func NewReplayServer(t Transcript) *ReplayServer {
+	return &ReplayServer{transcript: t}
+}
+
+// Dial returns client side of new in-memory connection to server. Use it with gkpxc.WithConn.
+// Every connection replays transcript from start.
+func (s *ReplayServer) Dial() net.Conn {
+	clientConn, serverConn := net.Pipe()
+
+	s.mu.Lock()
+	s.conns = append(s.conns, serverConn)
+	s.mu.Unlock()
+
+	go func() {
+		defer serverConn.Close()
+
+		if err := s.replay(serverConn); err != nil {
+			s.fail(err)
+		}
+	}()
+
+	return clientConn
+}
+
+// Err returns first replay error, i.e. unexpected client action or session finished before transcript end.
+func (s *ReplayServer) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+func (s *ReplayServer) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Close closes all connections.
+func (s *ReplayServer) Close() error {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = nil
+	s.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+
+	return nil
+}
+
+type replayConn struct {
+	conn      net.Conn
+	enc       *json.Encoder
+	serverKey *[gkpxc.KeySize]byte
+	sharedKey *[gkpxc.KeySize]byte
+
+	// responses are bound to last request with same action because async ones (i.e. "generate-password")
+	// may be sent after responses to following requests
+	requests    map[string]gkpxc.Message
+	lastRequest gkpxc.Message
+}
+
+func (s *ReplayServer) replay(conn net.Conn) error {
+	if len(s.transcript.ServerPrivateKey) != gkpxc.KeySize {
+		return fmt.Errorf("invalid server private key in transcript")
+	}
+
+	c := &replayConn{
+		conn:      conn,
+		enc:       json.NewEncoder(conn),
+		serverKey: (*[gkpxc.KeySize]byte)(s.transcript.ServerPrivateKey),
+		requests:  make(map[string]gkpxc.Message),
+	}
+	dec := json.NewDecoder(conn)
+
+	for i, step := range s.transcript.Steps {
+		if step.From == FromServer {
+			if err := c.send(step); err != nil {
+				return fmt.Errorf("step %d: %w", i, err)
+			}
+
+			continue
+		}
+
+		var req gkpxc.Message
+		if err := dec.Decode(&req); err != nil {
+			return fmt.Errorf("step %d: expected %s request: %w", i, step.Message.Action, err)
+		}
+
+		if err := c.receive(req, step); err != nil {
+			// error saved before reply so client sees it after response
+			s.fail(fmt.Errorf("step %d: %w", i, err))
+
+			_ = c.enc.Encode(gkpxc.Message{
+				ErrorFields: gkpxc.ErrorFields{Text: "replay: " + err.Error(), Code: gkpxc.ErrCodeIncorrectAction},
+				Action:      req.Action,
+			})
+
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (c *replayConn) receive(req gkpxc.Message, step Step) error {
+	if req.Action != step.Message.Action {
+		return fmt.Errorf("unexpected action %s, expected %s", req.Action, step.Message.Action)
+	}
+
+	c.requests[req.Action], c.lastRequest = req, req
+
+	if req.Action == "change-public-keys" {
+		if len(req.PublicKey) != gkpxc.KeySize {
+			return fmt.Errorf("invalid client public key")
+		}
+
+		c.sharedKey = new([gkpxc.KeySize]byte)
+		box.Precompute(c.sharedKey, (*[gkpxc.KeySize]byte)(req.PublicKey), c.serverKey)
+
+		return nil
+	}
+
+	if len(step.Payload) == 0 {
+		return nil
+	}
+
+	if _, err := openMessage(req, c.sharedKey, c.sharedKey); err != nil {
+		return fmt.Errorf("%s request: %w", req.Action, err)
+	}
+
+	return nil
+}
+
+func (c *replayConn) send(step Step) error {
+	msg := step.Message
+	if msg.Action == "database-locked" || msg.Action == "database-unlocked" {
+		// signals are not replies, sent as is
+		return c.enc.Encode(msg)
+	}
+
+	req, ok := c.requests[msg.Action]
+	if !ok {
+		req = c.lastRequest // i.e. error reply without action
+	}
+
+	// recorded values are bound to recorded requests, live ones must be used
+	if msg.RequestID != "" {
+		msg.RequestID = req.RequestID
+	}
+
+	switch {
+	case len(step.Payload) > 0:
+		if c.sharedKey == nil {
+			return fmt.Errorf("public keys not exchanged")
+		}
+
+		msg.Nonce = incrementNonce(req.Nonce)
+
+		payload, err := replacePayloadFields(step.Payload, map[string]interface{}{
+			"nonce":     msg.Nonce,
+			"requestID": req.RequestID,
+		})
+		if err != nil {
+			return err
+		}
+
+		msg.Message = box.SealAfterPrecomputation(nil, payload, (*[gkpxc.NonceSize]byte)(msg.Nonce), c.sharedKey)
+	case msg.Nonce != nil:
+		msg.Nonce = incrementNonce(req.Nonce)
+	}
+
+	return c.enc.Encode(msg)
+}
+
+// replacePayloadFields replaces fields duplicated by KeepassXC inside encrypted payload. Fields absent in payload
+// are not added.
+func replacePayloadFields(payload json.RawMessage, values map[string]interface{}) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, fmt.Errorf("parse payload: %w", err)
+	}
+
+	replaced := false
+
+	for name, value := range values {
+		if _, ok := fields[name]; !ok {
+			continue
+		}
+
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		fields[name], replaced = encoded, true
+	}
+
+	if !replaced {
+		return payload, nil
+	}
+
+	return json.Marshal(fields)
+}
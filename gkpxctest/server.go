@@ -0,0 +1,443 @@
+// Package gkpxctest provides in-process fake KeepassXC server for tests.
+package gkpxctest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/xakep666/gkpxc"
+)
+
+// Version is a KeepassXC version reported by Server.
+const Version = "2.7.4"
+
+// Entry is a database entry stored in Server.
+type Entry struct {
+	UUID      string
+	Name      string
+	URL       string
+	Login     string
+	Password  string
+	GroupUUID string
+	TOTP      string
+	Expired   bool
+
+	// StringFields are advanced string fields without gkpxc.StringFieldPrefix.
+	StringFields map[string]string
+}
+
+// Server is a fake KeepassXC browser integration server. It speaks real encrypted protocol
+// and keeps database in memory. Server is safe for concurrent use.
+type Server struct {
+	mu           sync.Mutex
+	hash         string
+	version      string
+	locked       bool
+	unlockable   bool
+	approve      func(action string) bool
+	generate     func() (string, bool)
+	associations map[string][]byte // id -> public id key
+	entries      []Entry
+	root         gkpxc.DatabaseGroup
+	conns        []*serverConn
+	closed       bool
+}
+
+// NewServer creates server with unlocked empty database.
+func NewServer() *Server {
+	return &Server{
+		hash:         randomHex(32),
+		version:      Version,
+		associations: make(map[string][]byte),
+		root:         gkpxc.DatabaseGroup{Name: "Root", UUID: randomHex(16)},
+	}
+}
+
+// DatabaseHash returns hash of database, i.e. to store association credentials.
+func (s *Server) DatabaseHash() string {
+	return s.hash
+}
+
+// SetVersion changes reported KeepassXC version (Version by default) for new connections,
+// i.e. to test capability detection. Actions are not restricted by version.
+func (s *Server) SetVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version = version
+}
+
+// SetLocked locks or unlocks database. If unlockable is set database is unlocked on request with "triggerUnlock".
+func (s *Server) SetLocked(locked, unlockable bool) {
+	s.mu.Lock()
+	s.locked, s.unlockable = locked, unlockable
+	s.mu.Unlock()
+
+	s.signalLockState(locked)
+}
+
+// signalLockState sends lock state signal to all connections like KeepassXC does.
+func (s *Server) signalLockState(locked bool) {
+	s.mu.Lock()
+	conns := append([]*serverConn(nil), s.conns...)
+	s.mu.Unlock()
+
+	action := "database-unlocked"
+	if locked {
+		action = "database-locked"
+	}
+
+	for _, c := range conns {
+		c.send(gkpxc.Message{Action: action})
+	}
+}
+
+// SetApprover sets function which decides whether actions requiring user confirmation
+// ("associate", "create-new-group", "delete-entry") are allowed. All actions approved by default.
+func (s *Server) SetApprover(approve func(action string) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.approve = approve
+}
+
+// SetPasswordGenerator sets function emulating password generator dialog. It's called in background
+// on "generate-password" request and may block like user does. If it returns false dialog considered
+// closed without accepting password. By default, random password is accepted immediately.
+func (s *Server) SetPasswordGenerator(generate func() (password string, ok bool)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generate = generate
+}
+
+// AddAssociation registers association credentials like they were created earlier.
+func (s *Server) AddAssociation(cred gkpxc.AssociationCredentials) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.associations[cred.ID] = append([]byte(nil), cred.PublicKey[:]...)
+}
+
+// RemoveAssociation removes association like user does in database settings.
+func (s *Server) RemoveAssociation(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.associations, id)
+}
+
+// Associations returns count of registered associations.
+func (s *Server) Associations() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.associations)
+}
+
+// AddEntry adds entry to database. UUID and name generated if empty. UUID returned.
+func (s *Server) AddEntry(entry Entry) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry.UUID == "" {
+		entry.UUID = randomHex(16)
+	}
+
+	if entry.Name == "" {
+		entry.Name = hostOf(entry.URL)
+	}
+
+	if entry.GroupUUID == "" {
+		entry.GroupUUID = s.root.UUID
+	}
+
+	s.entries = append(s.entries, entry)
+
+	return entry.UUID
+}
+
+// Entries returns copy of database entries.
+func (s *Server) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Entry(nil), s.entries...)
+}
+
+// Groups returns copy of group hierarchy starting from root.
+func (s *Server) Groups() gkpxc.DatabaseGroup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return copyGroup(s.root)
+}
+
+// Dial returns client side of new in-memory connection to server. Use it with gkpxc.WithConn.
+func (s *Server) Dial() net.Conn {
+	clientConn, serverConn := net.Pipe()
+	s.Serve(serverConn)
+
+	return clientConn
+}
+
+// Serve serves connection in background until it closed.
+func (s *Server) Serve(conn net.Conn) {
+	c := &serverConn{server: s, conn: conn}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+
+		return
+	}
+
+	s.conns = append(s.conns, c)
+	s.mu.Unlock()
+
+	go c.serve()
+}
+
+// Close closes all served connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns, s.closed = nil, true
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		c.conn.Close()
+	}
+
+	return nil
+}
+
+func (s *Server) approved(action string) bool {
+	return s.approve == nil || s.approve(action)
+}
+
+func randomHex(size int) string {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Errorf("random read: %w", err))
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	return u.Host
+}
+
+func copyGroup(g gkpxc.DatabaseGroup) gkpxc.DatabaseGroup {
+	ret := gkpxc.DatabaseGroup{Name: g.Name, UUID: g.UUID}
+	for _, child := range g.Children {
+		ret.Children = append(ret.Children, copyGroup(child))
+	}
+
+	return ret
+}
+
+type serverConn struct {
+	server *Server
+	conn   net.Conn
+
+	writeMu   sync.Mutex
+	sharedKey *[gkpxc.KeySize]byte
+	version   string
+}
+
+func (c *serverConn) send(msg gkpxc.Message) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = json.NewEncoder(c.conn).Encode(msg)
+}
+
+func (c *serverConn) serve() {
+	defer c.conn.Close()
+
+	dec := json.NewDecoder(c.conn)
+	for {
+		var req gkpxc.Message
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+
+		if req.Action == "generate-password" && c.sharedKey != nil {
+			// KeepassXC before 2.7.0 responded immediately
+			if version, _ := gkpxc.ParseVersion(c.version); version.AtLeast(2, 7, 0) {
+				// generator may block like user does, other requests must be served meanwhile
+				go func() { c.send(c.generatePassword(req, false)) }()
+			} else {
+				c.send(c.generatePassword(req, true))
+			}
+
+			continue
+		}
+
+		resp := c.handle(req)
+		c.send(resp)
+
+		if req.Action == "lock-database" && resp.Code == 0 {
+			c.server.signalLockState(true)
+		}
+	}
+}
+
+func (c *serverConn) handle(req gkpxc.Message) gkpxc.Message {
+	respNonce := incrementNonce(req.Nonce)
+
+	if req.Action == "change-public-keys" {
+		if len(req.PublicKey) != gkpxc.KeySize {
+			return errorMessage(req.Action, gkpxc.ErrCodeClientPublicKeyNotReceived, "client public key not received")
+		}
+
+		pub, priv, err := box.GenerateKey(rand.Reader)
+		if err != nil {
+			return errorMessage(req.Action, gkpxc.ErrCodeKeyChangeFailed, err.Error())
+		}
+
+		c.sharedKey = new([gkpxc.KeySize]byte)
+		box.Precompute(c.sharedKey, (*[gkpxc.KeySize]byte)(req.PublicKey), priv)
+
+		success := true
+
+		c.server.mu.Lock()
+		c.version = c.server.version
+		c.server.mu.Unlock()
+
+		return gkpxc.Message{
+			ErrorFields: gkpxc.ErrorFields{Success: &success},
+			Action:      req.Action,
+			Nonce:       respNonce,
+			PublicKey:   (*pub)[:],
+			Version:     c.version,
+		}
+	}
+
+	if c.sharedKey == nil {
+		return errorMessage(req.Action, gkpxc.ErrCodeClientPublicKeyNotReceived, "public keys not exchanged")
+	}
+
+	payload, errResp := c.decrypt(req)
+	if errResp != nil {
+		return errorMessage(req.Action, errResp.Code, errResp.Text)
+	}
+
+	resp, errResp := c.server.dispatch(req.Action, req.TriggerUnlock, payload)
+	if errResp != nil {
+		return errorMessage(req.Action, errResp.Code, errResp.Text)
+	}
+
+	return c.encrypt(req.Action, resp, respNonce)
+}
+
+func (c *serverConn) decrypt(req gkpxc.Message) (map[string]json.RawMessage, *gkpxc.ErrorResponse) {
+	if len(req.Nonce) != gkpxc.NonceSize {
+		return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeCannotDecryptMessage, Text: "invalid nonce"}
+	}
+
+	decrypted, ok := box.OpenAfterPrecomputation(nil, req.Message, (*[gkpxc.NonceSize]byte)(req.Nonce), c.sharedKey)
+	if !ok {
+		return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeCannotDecryptMessage, Text: "cannot decrypt message"}
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(decrypted, &payload); err != nil {
+		return nil, &gkpxc.ErrorResponse{Code: gkpxc.ErrCodeCannotDecryptMessage, Text: "cannot decode message"}
+	}
+
+	return payload, nil
+}
+
+func (c *serverConn) encrypt(action string, resp response, respNonce []byte) gkpxc.Message {
+	resp["action"] = action
+	resp["version"] = c.version
+	resp["nonce"] = respNonce
+	resp["success"] = "true"
+
+	plain, err := json.Marshal(resp)
+	if err != nil {
+		return errorMessage(action, gkpxc.ErrCodeCannotEncryptMessage, err.Error())
+	}
+
+	return gkpxc.Message{
+		Action:  action,
+		Message: box.SealAfterPrecomputation(nil, plain, (*[gkpxc.NonceSize]byte)(respNonce), c.sharedKey),
+		Nonce:   respNonce,
+	}
+}
+
+// generatePassword emulates password generator. Since 2.7.0 KeepassXC sends password when user accepts it
+// in dialog, older versions respond immediately with password in "entries".
+func (c *serverConn) generatePassword(req gkpxc.Message, legacy bool) gkpxc.Message {
+	payload, errResp := c.decrypt(req)
+	if errResp != nil {
+		return errorMessage(req.Action, errResp.Code, errResp.Text)
+	}
+
+	var genReq gkpxc.GeneratePasswordRequest
+	_ = unmarshalPayload(payload, &genReq)
+
+	c.server.mu.Lock()
+	generate := c.server.generate
+	c.server.mu.Unlock()
+
+	password, ok := randomHex(16), true
+	if generate != nil {
+		password, ok = generate()
+	}
+
+	if !ok {
+		msg := errorMessage(req.Action, gkpxc.ErrCodeActionCancelledOrDenied, "Action cancelled or denied")
+		msg.RequestID = genReq.RequestID
+
+		return msg
+	}
+
+	resp := response{"password": password, "requestID": genReq.RequestID}
+	if legacy {
+		resp = response{"entries": []response{{"login": fmt.Sprint(len(password) * 4), "password": password}}}
+	}
+
+	return c.encrypt(req.Action, resp, incrementNonce(req.Nonce))
+}
+
+func errorMessage(action string, code int, text string) gkpxc.Message {
+	return gkpxc.Message{
+		ErrorFields: gkpxc.ErrorFields{Text: text, Code: code},
+		Action:      action,
+	}
+}
+
+func incrementNonce(nonce []byte) []byte {
+	ret := append([]byte{}, nonce...)
+
+	c := uint16(1)
+	for i := range ret {
+		c += uint16(ret[i])
+		ret[i] = byte(c)
+		c >>= 8
+	}
+
+	return ret
+}
+
+func matchURL(entryURL, requestURL string) bool {
+	return strings.EqualFold(hostOf(entryURL), hostOf(requestURL))
+}
@@ -0,0 +1,124 @@
+package gkpxctest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/gkpxctest"
+)
+
+func TestServer(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	uuid := srv.AddEntry(gkpxctest.Entry{URL: "https://example.com", Login: "user", Password: "pass", TOTP: "123456"})
+
+	ctx := context.Background()
+
+	client, err := gkpxc.NewClient(ctx, gkpxc.WithConn(srv.Dial()))
+	if err != nil {
+		t.Fatal("NewClient", err)
+	}
+
+	defer client.Close()
+
+	hash, err := client.GetDatabaseHash(ctx, false)
+	if err != nil {
+		t.Fatal("GetDatabaseHash", err)
+	}
+
+	if hash.Hash != srv.DatabaseHash() {
+		t.Fatalf("Unexpected hash %s, expected %s", hash.Hash, srv.DatabaseHash())
+	}
+
+	if err = client.Associate(ctx); err != nil {
+		t.Fatal("Associate", err)
+	}
+
+	old, err := client.RotateAssociation(ctx)
+	if err != nil {
+		t.Fatal("RotateAssociation", err)
+	}
+
+	if old.ID == client.AssociationCredentials().ID || old.PublicKey == client.AssociationCredentials().PublicKey {
+		t.Fatalf("Association not rotated")
+	}
+
+	logins, err := client.GetLogins(ctx, gkpxc.GetLoginsRequest{URL: "https://example.com/login"})
+	if err != nil {
+		t.Fatal("GetLogins", err)
+	}
+
+	if len(logins.Entries) != 1 || logins.Entries[0].UUID != uuid || logins.Entries[0].Password != "pass" {
+		t.Fatalf("Unexpected entries %+v", logins.Entries)
+	}
+
+	totp, err := client.GetTOTP(ctx, gkpxc.GetTOTPRequest{UUID: uuid})
+	if err != nil {
+		t.Fatal("GetTOTP", err)
+	}
+
+	if totp.TOTP != "123456" {
+		t.Fatalf("Unexpected TOTP %s", totp.TOTP)
+	}
+
+	_, err = client.GetLogins(ctx, gkpxc.GetLoginsRequest{URL: "https://other.com"})
+	if !gkpxc.IsErrorCode(err, gkpxc.ErrCodeNoLoginsFound) {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// credentials associated with another database must not be used
+	cred := *client.AssociationCredentials()
+	cred.Hash = "other"
+	client.SetAssociationCredentials(&cred)
+
+	err = client.SetLogin(ctx, gkpxc.SetLoginRequest{URL: "https://example.com", Login: "user", Password: "new"})
+	if !errors.Is(err, gkpxc.ErrDatabaseMismatch) {
+		t.Fatalf("Unexpected error %v, expected ErrDatabaseMismatch", err)
+	}
+}
+
+func TestServer_GeneratePassword_blocked(t *testing.T) {
+	srv := gkpxctest.NewTestServer(t)
+
+	started, answers := make(chan struct{}), make(chan string)
+	srv.SetPasswordGenerator(func() (string, bool) {
+		close(started)
+		return <-answers, true
+	})
+
+	ctx := context.Background()
+	client := gkpxctest.NewAssociatedClient(t, srv)
+
+	type result struct {
+		password string
+		err      error
+	}
+
+	generated := make(chan result, 1)
+
+	go func() {
+		password, err := client.GeneratePassword(ctx)
+		generated <- result{password: password, err: err}
+	}()
+
+	<-started
+
+	// dialog is open but connection must serve other requests
+	hash, err := client.GetDatabaseHash(ctx, false)
+	if err != nil {
+		t.Fatal("GetDatabaseHash", err)
+	}
+
+	if hash.Hash != srv.DatabaseHash() {
+		t.Fatalf("Unexpected hash %s, expected %s", hash.Hash, srv.DatabaseHash())
+	}
+
+	answers <- "generated"
+
+	if res := <-generated; res.err != nil || res.password != "generated" {
+		t.Fatalf("Unexpected generation result %q: %v", res.password, res.err)
+	}
+}
@@ -0,0 +1,37 @@
+package gkpxctest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xakep666/gkpxc"
+)
+
+// NewTestServer creates server with NewServer and closes it on test cleanup.
+func NewTestServer(tb testing.TB) *Server {
+	tb.Helper()
+
+	srv := NewServer()
+	tb.Cleanup(func() { srv.Close() })
+
+	return srv
+}
+
+// NewAssociatedClient connects to server with provided options and associates with it.
+// Client is closed on test cleanup, test fails if connection or association fails.
+func NewAssociatedClient(tb testing.TB, srv *Server, opts ...gkpxc.ClientOption) *gkpxc.Client {
+	tb.Helper()
+
+	client, err := gkpxc.NewClient(context.Background(), append([]gkpxc.ClientOption{gkpxc.WithConn(srv.Dial())}, opts...)...)
+	if err != nil {
+		tb.Fatal("NewClient", err)
+	}
+
+	tb.Cleanup(func() { client.Close() })
+
+	if err = client.Associate(context.Background()); err != nil {
+		tb.Fatal("Associate", err)
+	}
+
+	return client
+}
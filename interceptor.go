@@ -0,0 +1,32 @@
+package gkpxc
+
+import (
+	"context"
+	"fmt"
+)
+
+// Invoker performs exchange with KeepassXC. Request is a typed request value (i.e. GetLoginsRequest),
+// response is a pointer to typed response (i.e. *GetLoginsResponse) filled by invoker.
+// For Client.Call they're values passed by caller. Handshake ("change-public-keys" action) uses Message and *Message.
+type Invoker func(ctx context.Context, action string, req, resp interface{}) error
+
+// Interceptor wraps exchange with KeepassXC. It may inspect or modify request and response, call next
+// multiple times (i.e. to retry) or not call it at all (i.e. to answer from cache or enforce policy).
+// Handshake request must be passed to next as Message. Note that with WithSecretBytes "get-logins" response has internal type.
+type Interceptor func(ctx context.Context, action string, req, resp interface{}, next Invoker) error
+
+// intercept calls invoker through interceptors chain. First interceptor is outermost.
+func (c *Client) intercept(ctx context.Context, action string, req, resp interface{}, invoker Invoker) error {
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := c.interceptors[i], invoker
+		invoker = func(ctx context.Context, action string, req, resp interface{}) error {
+			return interceptor(ctx, action, req, resp, next)
+		}
+	}
+
+	return invoker(ctx, action, req, resp)
+}
+
+func invalidInvokerArgs(req, resp interface{}) error {
+	return fmt.Errorf("invalid invoker arguments: request %T, response %T", req, resp)
+}
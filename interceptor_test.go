@@ -0,0 +1,107 @@
+package gkpxc_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/gkpxctest"
+)
+
+func TestClient_Interceptor(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	uuid := srv.AddEntry(gkpxctest.Entry{URL: "https://example.com", Login: "user", Password: "pass"})
+
+	ctx := context.Background()
+	errPolicy := errors.New("denied by policy")
+
+	var audit []string
+
+	auditor := func(ctx context.Context, action string, req, resp interface{}, next gkpxc.Invoker) error {
+		audit = append(audit, action)
+		return next(ctx, action, req, resp)
+	}
+
+	policy := func(ctx context.Context, action string, req, resp interface{}, next gkpxc.Invoker) error {
+		if _, ok := req.(gkpxc.DeleteEntryRequest); ok {
+			return errPolicy
+		}
+
+		// rewrite request and inspect typed response
+		if getLogins, ok := req.(gkpxc.GetLoginsRequest); ok {
+			getLogins.URL = "https://example.com"
+			if err := next(ctx, action, getLogins, resp); err != nil {
+				return err
+			}
+
+			resp.(*gkpxc.GetLoginsResponse).Entries[0].Password = "intercepted"
+
+			return nil
+		}
+
+		return next(ctx, action, req, resp)
+	}
+
+	client := gkpxctest.NewAssociatedClient(t, srv, gkpxc.WithInterceptor(auditor), gkpxc.WithInterceptor(policy))
+
+	logins, err := client.GetLogins(ctx, gkpxc.GetLoginsRequest{URL: "https://other.com"})
+	if err != nil {
+		t.Fatal("GetLogins", err)
+	}
+
+	if len(logins.Entries) != 1 || logins.Entries[0].UUID != uuid || logins.Entries[0].Password != "intercepted" {
+		t.Fatalf("Unexpected entries %+v", logins.Entries)
+	}
+
+	if err = client.DeleteEntry(ctx, gkpxc.DeleteEntryRequest{UUID: uuid}); !errors.Is(err, errPolicy) {
+		t.Fatalf("Unexpected error %v, expected policy error", err)
+	}
+
+	if len(srv.Entries()) != 1 {
+		t.Fatalf("Entry deleted")
+	}
+
+	expectAudit := []string{"change-public-keys", "associate", "test-associate", "get-logins", "test-associate", "delete-entry"}
+	if !reflect.DeepEqual(audit, expectAudit) {
+		t.Fatalf("Got actions %v, expected %v", audit, expectAudit)
+	}
+}
+
+func TestClient_Interceptor_retry(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	attempts := 0
+
+	retry := func(ctx context.Context, action string, req, resp interface{}, next gkpxc.Invoker) error {
+		for {
+			attempts++
+
+			err := next(ctx, action, req, resp)
+			if !gkpxc.IsErrorCode(err, gkpxc.ErrCodeDatabaseNotOpened) || attempts > 1 {
+				return err
+			}
+
+			srv.SetLocked(false, false)
+		}
+	}
+
+	client := gkpxctest.NewAssociatedClient(t, srv, gkpxc.WithInterceptor(retry))
+
+	srv.SetLocked(true, false)
+
+	attempts = 0
+
+	hash, err := client.GetDatabaseHash(context.Background(), false)
+	if err != nil {
+		t.Fatal("GetDatabaseHash", err)
+	}
+
+	if hash.Hash != srv.DatabaseHash() || attempts != 2 {
+		t.Fatalf("Unexpected hash %s after %d attempts", hash.Hash, attempts)
+	}
+}
@@ -0,0 +1,267 @@
+// Package bootstrap contains helpers shared by utilities to get ready-to-use KeepassXC client.
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/99designs/keyring"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/filestore"
+)
+
+// SetupKeyring opens os-specific keyring to store association credentials.
+// File backend is allowed only if askPassCmd is not empty. This command used to prompt file password.
+func SetupKeyring(service, askPassCmd string) (keyring.Keyring, error) {
+	backends := []keyring.BackendType{
+		// Windows
+		keyring.WinCredBackend,
+		// MacOS
+		keyring.KeychainBackend,
+		// Linux
+		keyring.KWalletBackend,
+		keyring.SecretServiceBackend,
+	}
+
+	if askPassCmd != "" {
+		backends = append(backends, keyring.FileBackend)
+	}
+
+	return keyring.Open(keyring.Config{
+		AllowedBackends:          backends,
+		WinCredPrefix:            service,
+		KeychainName:             "login",
+		KeychainTrustApplication: true,
+		KWalletAppID:             service,
+		KWalletFolder:            service,
+		LibSecretCollectionName:  service,
+		FileDir:                  fileBackendDir(service),
+		FilePasswordFunc: func(prompt string) (string, error) {
+			out, err := exec.Command(askPassCmd, prompt).Output()
+			return string(out), err
+		},
+	})
+}
+
+// ErrNoStoreKey returned if no key source configured for file store.
+var ErrNoStoreKey = errors.New("no key source configured for association store")
+
+// FileStoreOptions configures encrypted file store for association credentials.
+// Key source is chosen in order: Credential, KeyFile, AskPassCmd.
+type FileStoreOptions struct {
+	// Path is a store file path. Default is "<user config dir>/<service>/associations.json".
+	Path string
+
+	// Credential is a name of systemd credential containing key.
+	Credential string
+
+	// KeyFile is a path to file containing key.
+	KeyFile string
+
+	// AskPassCmd used to prompt passphrase.
+	AskPassCmd string
+}
+
+// OpenFileStore opens encrypted file store to keep association credentials without OS keyring.
+func OpenFileStore(service string, opts FileStoreOptions) (keyring.Keyring, error) {
+	var keySource filestore.KeySource
+
+	switch {
+	case opts.Credential != "":
+		keySource = filestore.SystemdCredential(opts.Credential)
+	case opts.KeyFile != "":
+		keySource = filestore.KeyFile(opts.KeyFile)
+	case opts.AskPassCmd != "":
+		keySource = filestore.Passphrase(func() (string, error) {
+			out, err := exec.Command(opts.AskPassCmd, "Association store passphrase").Output()
+			return strings.TrimRight(string(out), "\r\n"), err
+		})
+	default:
+		return nil, ErrNoStoreKey
+	}
+
+	path := opts.Path
+	if path == "" {
+		dir := fileBackendDir(service)
+		if dir == "" {
+			return nil, fmt.Errorf("association store path is not set and user config dir unknown")
+		}
+
+		path = filepath.Join(dir, fileStoreName)
+	}
+
+	return filestore.Open(path, keySource)
+}
+
+const fileStoreName = "associations.json"
+
+var (
+	// ErrAssociationDenied returned if user denied new association in KeepassXC.
+	ErrAssociationDenied = errors.New("association denied")
+
+	// ErrAssociationRequired returned in non-interactive mode if no association credentials stored.
+	ErrAssociationRequired = errors.New("association required but not allowed in non-interactive mode")
+
+	// ErrAssociationRevoked returned if stored association was removed in KeepassXC and re-association was not confirmed.
+	ErrAssociationRevoked = errors.New("association revoked in KeepassXC")
+)
+
+// Connector creates clients with association credentials stored in keyring by database hash.
+type Connector struct {
+	Keyring keyring.Keyring
+
+	// NonInteractive disables actions which require user presence: database unlock and new association.
+	NonInteractive bool
+
+	// NewClient creates client. Default is gkpxc.NewClient.
+	NewClient func(ctx context.Context, opts ...gkpxc.ClientOption) (*gkpxc.Client, error)
+
+	// ConfirmReassociation is called when stored association was revoked in KeepassXC (i.e. removed in database settings).
+	// Stored credentials are removed anyway, new association requested only if it returns true. Default allows it.
+	ConfirmReassociation func(ctx context.Context, dbHash string) bool
+
+	ClientOptions []gkpxc.ClientOption
+}
+
+// Connect creates client and sets association credentials stored in keyring by database hash.
+// If credentials not found new association requested and stored into keyring.
+func Connect(ctx context.Context, kr keyring.Keyring, opts ...gkpxc.ClientOption) (*gkpxc.Client, error) {
+	return Connector{Keyring: kr, ClientOptions: opts}.Connect(ctx)
+}
+
+// Connect creates client and sets association credentials stored in keyring by database hash.
+// If credentials not found or revoked new association requested (if allowed) and stored into keyring.
+func (c Connector) Connect(ctx context.Context) (*gkpxc.Client, error) {
+	newClient := c.NewClient
+	if newClient == nil {
+		newClient = gkpxc.NewClient
+	}
+
+	client, err := newClient(ctx, c.ClientOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("keepassxc connect failed: %w", err)
+	}
+
+	if err = c.associate(ctx, client); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (c Connector) associate(ctx context.Context, client *gkpxc.Client) error {
+	dbHash, err := client.GetDatabaseHash(ctx, !c.NonInteractive)
+	if err != nil {
+		return fmt.Errorf("get database hash failed: %w", err)
+	}
+
+	secret, err := c.Keyring.Get(dbHash.Hash)
+	switch {
+	case errors.Is(err, nil):
+		var cred gkpxc.AssociationCredentials
+		if err = json.Unmarshal(secret.Data, &cred); err != nil {
+			return c.newAssociation(ctx, client, dbHash.Hash)
+		}
+
+		client.SetAssociationCredentials(&cred)
+
+		err = client.TestAssociate(ctx)
+		switch {
+		case errors.Is(err, nil):
+			return nil
+		case gkpxc.IsErrorCode(err, gkpxc.ErrCodeAssociationFailed):
+			return c.reassociate(ctx, client, dbHash.Hash)
+		default:
+			return fmt.Errorf("test association failed: %w", err)
+		}
+	case errors.Is(err, keyring.ErrKeyNotFound):
+		return c.newAssociation(ctx, client, dbHash.Hash)
+	default:
+		return fmt.Errorf("association key get failed: %w", err)
+	}
+}
+
+// reassociate discards revoked credentials and requests new association if confirmed.
+func (c Connector) reassociate(ctx context.Context, client *gkpxc.Client, dbHash string) error {
+	client.SetAssociationCredentials(nil)
+
+	if err := c.Keyring.Remove(dbHash); err != nil && !errors.Is(err, keyring.ErrKeyNotFound) {
+		return fmt.Errorf("remove revoked association failed: %w", err)
+	}
+
+	if c.ConfirmReassociation != nil && !c.ConfirmReassociation(ctx, dbHash) {
+		return ErrAssociationRevoked
+	}
+
+	return c.newAssociation(ctx, client, dbHash)
+}
+
+func (c Connector) newAssociation(ctx context.Context, client *gkpxc.Client, dbHash string) error {
+	if c.NonInteractive {
+		return ErrAssociationRequired
+	}
+
+	err := client.Associate(ctx)
+	switch {
+	case errors.Is(err, nil):
+	case gkpxc.IsErrorCode(err, gkpxc.ErrCodeActionCancelledOrDenied),
+		gkpxc.IsErrorCode(err, gkpxc.ErrCodeAssociationFailed):
+		return fmt.Errorf("%w: %s", ErrAssociationDenied, err)
+	default:
+		return fmt.Errorf("association failed: %w", err)
+	}
+
+	return c.store(dbHash, client.AssociationCredentials())
+}
+
+// Rotate replaces client association with a new one and stores it. Old association is kept in KeepassXC
+// database settings, user should remove it there.
+func (c Connector) Rotate(ctx context.Context, client *gkpxc.Client) error {
+	old, err := client.RotateAssociation(ctx)
+	switch {
+	case errors.Is(err, nil):
+	case gkpxc.IsErrorCode(err, gkpxc.ErrCodeActionCancelledOrDenied),
+		gkpxc.IsErrorCode(err, gkpxc.ErrCodeAssociationFailed):
+		return fmt.Errorf("%w: %s", ErrAssociationDenied, err)
+	default:
+		return fmt.Errorf("rotate association failed: %w", err)
+	}
+
+	if err = c.store(client.AssociationCredentials().Hash, client.AssociationCredentials()); err != nil {
+		// keep old credentials usable for this client since new ones are lost
+		client.SetAssociationCredentials(old)
+		return err
+	}
+
+	return nil
+}
+
+func (c Connector) store(dbHash string, cred *gkpxc.AssociationCredentials) error {
+	serialized, err := json.Marshal(cred)
+	if err != nil {
+		return fmt.Errorf("serialize association credentials failed: %w", err)
+	}
+
+	if err = c.Keyring.Set(keyring.Item{Key: dbHash, Data: serialized}); err != nil {
+		return fmt.Errorf("store association credentials failed: %w", err)
+	}
+
+	return nil
+}
+
+func fileBackendDir(service string) string {
+	cfgDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(cfgDir, service)
+}
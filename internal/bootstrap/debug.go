@@ -0,0 +1,29 @@
+package bootstrap
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xakep666/gkpxc"
+)
+
+// DebugFlag enables protocol tracing in utilities.
+const DebugFlag = "--debug"
+
+// ParseDebugFlag removes leading DebugFlag (or "-debug") from args and reports if it was present.
+func ParseDebugFlag(args []string) ([]string, bool) {
+	if len(args) > 0 && (args[0] == DebugFlag || args[0] == strings.TrimPrefix(DebugFlag, "-")) {
+		return args[1:], true
+	}
+
+	return args, false
+}
+
+// DebugOptions returns client options to write redacted protocol trace into w.
+func DebugOptions(w io.Writer) []gkpxc.ClientOption {
+	return []gkpxc.ClientOption{gkpxc.WithTracer(func(e gkpxc.TraceEvent) {
+		fmt.Fprintf(w, "keepassxc: %s in %s, nonce valid: %t, error code: %d, error: %v\n  request: %s\n  response: %s\n",
+			e.Action, e.Duration, e.NonceValid, e.ErrorCode, e.Err, e.Request, e.Response)
+	})}
+}
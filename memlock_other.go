@@ -0,0 +1,8 @@
+//go:build !linux
+
+package gkpxc
+
+// lockMemory is supported only on Linux.
+func lockMemory([]byte) error { return nil }
+
+func unlockMemory([]byte) error { return nil }
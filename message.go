@@ -1,9 +1,40 @@
 package gkpxc
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 )
 
+// Error codes which KeepassXC may return in ErrorResponse.Code.
+const (
+	ErrCodeDatabaseNotOpened          = 1
+	ErrCodeDatabaseHashNotReceived    = 2
+	ErrCodeClientPublicKeyNotReceived = 3
+	ErrCodeCannotDecryptMessage       = 4
+	ErrCodeTimeoutOrNotConnected      = 5
+	ErrCodeActionCancelledOrDenied    = 6
+	ErrCodeCannotEncryptMessage       = 7
+	ErrCodeAssociationFailed          = 8
+	ErrCodeKeyChangeFailed            = 9
+	ErrCodeEncryptionKeyUnrecognized  = 10
+	ErrCodeNoSavedDatabasesFound      = 11
+	ErrCodeIncorrectAction            = 12
+	ErrCodeEmptyMessageReceived       = 13
+	ErrCodeNoURLProvided              = 14
+	ErrCodeNoLoginsFound              = 15
+	ErrCodeNoGroupsFound              = 16
+	ErrCodeCannotCreateNewGroup       = 17
+	ErrCodeNoValidUUIDProvided        = 18
+	ErrCodeAccessToAllEntriesDenied   = 19
+)
+
+// IsErrorCode checks if err is ErrorResponse with given code.
+func IsErrorCode(err error, code int) bool {
+	var errResp *ErrorResponse
+	return errors.As(err, &errResp) && errResp.Code == code
+}
+
 // ErrorResponse returned as error if KeepassXC responds with error.
 type ErrorResponse struct {
 	Text string
@@ -54,8 +85,24 @@ type Message struct {
 	// PublicKey is base64-encoded public key used during handshake (Action="change-public-keys").
 	PublicKey []byte `json:"publicKey,omitempty"`
 
+	// RequestID identifies request if response comes asynchronously (i.e. for "generate-password").
+	RequestID string `json:"requestID,omitempty"`
+
 	// TriggerUnlock requests database unlock.
 	TriggerUnlock bool `json:"triggerUnlock,omitempty,string"`
+
+	Extra Extra `json:"-"` // fields not modeled by library
+}
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type plain Message
+	return unmarshalWithExtra(data, (*plain)(m), &m.Extra)
+}
+
+// MarshalJSON marshals message with Extra fields.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type plain Message
+	return marshalWithExtra((plain)(m), m.Extra)
 }
 
 // GetDatabaseHashRequest represents request for database hash.
@@ -69,6 +116,19 @@ type GetDatabaseHashResponse struct {
 
 	Hash    string `json:"hash"`
 	Version string `json:"version"`
+
+	Extra Extra `json:"-"` // fields not modeled by library
+}
+
+func (m *GetDatabaseHashResponse) UnmarshalJSON(data []byte) error {
+	type plain GetDatabaseHashResponse
+	return unmarshalWithExtra(data, (*plain)(m), &m.Extra)
+}
+
+// ParsedVersion returns parsed KeepassXC version. Zero (unknown) version returned if it's malformed.
+func (m GetDatabaseHashResponse) ParsedVersion() Version {
+	version, _ := ParseVersion(m.Version)
+	return version
 }
 
 // AssociateRequest represents new client association request.
@@ -89,6 +149,19 @@ type AssociateResponse struct {
 	ID      string `json:"id"`
 	Hash    string `json:"hash"`
 	Version string `json:"version"`
+
+	Extra Extra `json:"-"` // fields not modeled by library
+}
+
+func (m *AssociateResponse) UnmarshalJSON(data []byte) error {
+	type plain AssociateResponse
+	return unmarshalWithExtra(data, (*plain)(m), &m.Extra)
+}
+
+// ParsedVersion returns parsed KeepassXC version. Zero (unknown) version returned if it's malformed.
+func (m AssociateResponse) ParsedVersion() Version {
+	version, _ := ParseVersion(m.Version)
+	return version
 }
 
 // TestAssociateRequest represents client association test request.
@@ -109,6 +182,19 @@ type TestAssociateResponse struct {
 	ID      string `json:"id"`
 	Hash    string `json:"hash"`
 	Version string `json:"version"`
+
+	Extra Extra `json:"-"` // fields not modeled by library
+}
+
+func (m *TestAssociateResponse) UnmarshalJSON(data []byte) error {
+	type plain TestAssociateResponse
+	return unmarshalWithExtra(data, (*plain)(m), &m.Extra)
+}
+
+// ParsedVersion returns parsed KeepassXC version. Zero (unknown) version returned if it's malformed.
+func (m TestAssociateResponse) ParsedVersion() Version {
+	version, _ := ParseVersion(m.Version)
+	return version
 }
 
 // DatabaseGroup is item of group hierarchy.
@@ -134,10 +220,43 @@ type DatabaseGroupsResponse struct {
 	DefaultGroup            string         `json:"defaultGroup"`
 	DefaultGroupAlwaysAllow bool           `json:"defaultGroupAlwaysAllow"`
 	Groups                  GroupsEmbedded `json:"groups"`
+
+	Extra Extra `json:"-"` // fields not modeled by library
+}
+
+func (m *DatabaseGroupsResponse) UnmarshalJSON(data []byte) error {
+	type plain DatabaseGroupsResponse
+	return unmarshalWithExtra(data, (*plain)(m), &m.Extra)
+}
+
+// FindGroup looks up group by path relative to root group, i.e. "group1/group11".
+func (r DatabaseGroupsResponse) FindGroup(path string) (DatabaseGroup, bool) {
+	for _, root := range r.Groups.Groups {
+		if group, ok := root.find(strings.Split(strings.Trim(path, "/"), "/")); ok {
+			return group, true
+		}
+	}
+
+	return DatabaseGroup{}, false
+}
+
+func (g DatabaseGroup) find(path []string) (DatabaseGroup, bool) {
+	if len(path) == 0 {
+		return g, true
+	}
+
+	for _, child := range g.Children {
+		if child.Name == path[0] {
+			return child.find(path[1:])
+		}
+	}
+
+	return DatabaseGroup{}, false
 }
 
 // CreateNewGroupRequest represents new group creation request.
 type CreateNewGroupRequest struct {
+	// Name is a group name or path like "group1/group11". Missing groups in path created.
 	Name string `json:"groupName"`
 }
 
@@ -149,6 +268,13 @@ type CreateNewGroupResponse struct {
 
 	Name string `json:"name"`
 	UUID string `json:"uuid"`
+
+	Extra Extra `json:"-"` // fields not modeled by library
+}
+
+func (m *CreateNewGroupResponse) UnmarshalJSON(data []byte) error {
+	type plain CreateNewGroupResponse
+	return unmarshalWithExtra(data, (*plain)(m), &m.Extra)
 }
 
 type LoginKey struct {
@@ -181,11 +307,35 @@ type LoginEntry struct {
 	// Login contains user name.
 	Login string `json:"login"`
 
-	// Password contains password.
+	// Password contains password. It's empty if client created with WithSecretBytes.
 	Password string `json:"password"`
 
+	// PasswordBytes contains password if client created with WithSecretBytes. Caller should zero it after use.
+	PasswordBytes []byte `json:"-"`
+
 	// Expired is set when password expired according to entry expiration time.
 	Expired bool `json:"expired,string"`
+
+	// TOTP contains current TOTP if entry has it and KeepassXC supports it (see Capabilities.LoginTOTP).
+	TOTP string `json:"totp,omitempty"`
+
+	// StringFields contains advanced string fields (named with "KPH: " prefix).
+	// KeepassXC returns them only if "Return advanced string fields" option enabled.
+	StringFields []map[string]string `json:"stringFields,omitempty"`
+}
+
+// StringFieldPrefix is a prefix of advanced string fields names returned by KeepassXC.
+const StringFieldPrefix = "KPH: "
+
+// StringField returns value of advanced string field by name without StringFieldPrefix.
+func (e LoginEntry) StringField(name string) (string, bool) {
+	for _, fields := range e.StringFields {
+		if value, ok := fields[StringFieldPrefix+name]; ok {
+			return value, true
+		}
+	}
+
+	return "", false
 }
 
 // GetLoginsResponse contains found logins.
@@ -194,6 +344,27 @@ type GetLoginsResponse struct {
 
 	Count   int          `json:"count"`
 	Entries []LoginEntry `json:"entries"`
+
+	Extra Extra `json:"-"` // fields not modeled by library, not filled if client created with WithSecretBytes
+}
+
+func (m *GetLoginsResponse) UnmarshalJSON(data []byte) error {
+	type plain GetLoginsResponse
+	return unmarshalWithExtra(data, (*plain)(m), &m.Extra)
+}
+
+// secretLoginEntry is used to decode password without string allocation.
+type secretLoginEntry struct {
+	LoginEntry
+
+	Password secretBytes `json:"password"`
+}
+
+type secretGetLoginsResponse struct {
+	ErrorFields
+
+	Count   int                `json:"count"`
+	Entries []secretLoginEntry `json:"entries"`
 }
 
 // SetLoginRequest represents create or update login request.
@@ -213,6 +384,13 @@ func (SetLoginRequest) Action() string { return "set-login" }
 // SetLoginResponse returned on SetLoginRequest.
 type SetLoginResponse struct {
 	ErrorFields
+
+	Extra Extra `json:"-"` // fields not modeled by library
+}
+
+func (m *SetLoginResponse) UnmarshalJSON(data []byte) error {
+	type plain SetLoginResponse
+	return unmarshalWithExtra(data, (*plain)(m), &m.Extra)
 }
 
 // DeleteEntryRequest requests deletion entry by uuid.
@@ -225,16 +403,52 @@ func (DeleteEntryRequest) Action() string { return "delete-entry" }
 // DeleteEntryResponse returned on DeleteEntryRequest.
 type DeleteEntryResponse struct {
 	ErrorFields
+
+	Extra Extra `json:"-"` // fields not modeled by library
+}
+
+func (m *DeleteEntryResponse) UnmarshalJSON(data []byte) error {
+	type plain DeleteEntryResponse
+	return unmarshalWithExtra(data, (*plain)(m), &m.Extra)
 }
 
 // GeneratePasswordRequest requests to show generate password dialog.
-type GeneratePasswordRequest struct{}
+type GeneratePasswordRequest struct {
+	// RequestID is used to match response which comes when user accepts password.
+	RequestID string `json:"requestID,omitempty"`
+}
 
 func (GeneratePasswordRequest) Action() string { return "generate-password" }
 
+func (r GeneratePasswordRequest) requestID() string { return r.RequestID }
+
 // GeneratePasswordResponse returned on GeneratePasswordRequest.
 type GeneratePasswordResponse struct {
 	ErrorFields
+
+	// Password is a generated password (KeepassXC 2.7+).
+	Password string `json:"password"`
+
+	// Entries contain generated password in older KeepassXC versions.
+	Entries []LoginEntry `json:"entries"`
+
+	RequestID string `json:"requestID"`
+
+	Extra Extra `json:"-"` // fields not modeled by library
+}
+
+func (m *GeneratePasswordResponse) UnmarshalJSON(data []byte) error {
+	type plain GeneratePasswordResponse
+	return unmarshalWithExtra(data, (*plain)(m), &m.Extra)
+}
+
+// GeneratedPassword returns generated password from response of any KeepassXC version.
+func (r GeneratePasswordResponse) GeneratedPassword() string {
+	if r.Password == "" && len(r.Entries) > 0 {
+		return r.Entries[0].Password
+	}
+
+	return r.Password
 }
 
 // LockDatabaseRequest requests to lock database.
@@ -245,6 +459,13 @@ func (LockDatabaseRequest) Action() string { return "lock-database" }
 // LockDatabaseResponse returned on LockDatabaseRequest.
 type LockDatabaseResponse struct {
 	ErrorFields
+
+	Extra Extra `json:"-"` // fields not modeled by library
+}
+
+func (m *LockDatabaseResponse) UnmarshalJSON(data []byte) error {
+	type plain LockDatabaseResponse
+	return unmarshalWithExtra(data, (*plain)(m), &m.Extra)
 }
 
 // GetTOTPRequest requests current TOTP for entry.
@@ -260,6 +481,13 @@ type GetTOTPResponse struct {
 	ErrorFields
 
 	TOTP string `json:"totp"`
+
+	Extra Extra `json:"-"` // fields not modeled by library
+}
+
+func (m *GetTOTPResponse) UnmarshalJSON(data []byte) error {
+	type plain GetTOTPResponse
+	return unmarshalWithExtra(data, (*plain)(m), &m.Extra)
 }
 
 // AutoTypeRequest requests auto type by URL.
@@ -273,4 +501,11 @@ func (AutoTypeRequest) Action() string { return "request-autotype" }
 // AutoTypeResponse returned on AutoTypeRequest.
 type AutoTypeResponse struct {
 	ErrorFields
+
+	Extra Extra `json:"-"` // fields not modeled by library
+}
+
+func (m *AutoTypeResponse) UnmarshalJSON(data []byte) error {
+	type plain AutoTypeResponse
+	return unmarshalWithExtra(data, (*plain)(m), &m.Extra)
 }
@@ -0,0 +1,28 @@
+package gkpxc
+
+import "testing"
+
+func TestDatabaseGroupsResponse_FindGroup(t *testing.T) {
+	resp := DatabaseGroupsResponse{Groups: GroupsEmbedded{Groups: []DatabaseGroup{{
+		Name: "root",
+		UUID: "root-uuid",
+		Children: []DatabaseGroup{
+			{Name: "group1", UUID: "group1-uuid", Children: []DatabaseGroup{{Name: "group11", UUID: "group11-uuid"}}},
+			{Name: "group2", UUID: "group2-uuid"},
+		},
+	}}}}
+
+	for path, expectUUID := range map[string]string{
+		"group1":          "group1-uuid",
+		"group1/group11":  "group11-uuid",
+		"/group2/":        "group2-uuid",
+		"group2/group11":  "",
+		"group11":         "",
+		"group1/group111": "",
+	} {
+		group, ok := resp.FindGroup(path)
+		if ok != (expectUUID != "") || group.UUID != expectUUID {
+			t.Fatalf("Path %s: got %+v (found %t), expected uuid %q", path, group, ok, expectUUID)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package gkpxc
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrorClass is a coarse error category for metrics.
+type ErrorClass string
+
+// Error classes reported in ActionStats.
+const (
+	ErrorClassNone      ErrorClass = ""
+	ErrorClassKeepassXC ErrorClass = "keepassxc" // KeepassXC responded with error, see ActionStats.ErrorCode
+	ErrorClassCanceled  ErrorClass = "canceled"  // context canceled
+	ErrorClassTimeout   ErrorClass = "timeout"   // context deadline exceeded, i.e. user didn't answer prompt
+	ErrorClassClosing   ErrorClass = "closing"   // client closed
+	ErrorClassProtocol  ErrorClass = "protocol"  // invalid nonce or decryption failure
+	ErrorClassTransport ErrorClass = "transport" // other errors, i.e. connection failures
+)
+
+// ClassifyError returns error class.
+func ClassifyError(err error) ErrorClass {
+	var errResp *ErrorResponse
+
+	switch {
+	case err == nil:
+		return ErrorClassNone
+	case errors.As(err, &errResp):
+		return ErrorClassKeepassXC
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.Is(err, ErrClosing):
+		return ErrorClassClosing
+	case errors.Is(err, ErrInvalidNonce), errors.Is(err, ErrDecryptFailed):
+		return ErrorClassProtocol
+	default:
+		return ErrorClassTransport
+	}
+}
+
+// ActionStats describes completed exchange with KeepassXC.
+type ActionStats struct {
+	Action   string
+	Start    time.Time
+	Duration time.Duration
+
+	// RequestBytes and ResponseBytes are sizes of plaintext messages.
+	RequestBytes, ResponseBytes int
+
+	ErrorClass ErrorClass
+
+	// ErrorCode is a KeepassXC error code if ErrorClass is ErrorClassKeepassXC.
+	ErrorCode int
+}
+
+// Observer receives client instrumentation events. Methods are called synchronously so they should not block.
+type Observer interface {
+	// StartAction is called before exchange. Returned function is called after it.
+	StartAction(ctx context.Context, action string) func(ActionStats)
+
+	// LockStateChanged is called when KeepassXC signals database lock or unlock.
+	LockStateChanged(locked bool)
+}
+
+func (s *ActionStats) finish(err error) {
+	s.Duration = time.Since(s.Start)
+	s.ErrorClass = ClassifyError(err)
+
+	var errResp *ErrorResponse
+	if errors.As(err, &errResp) {
+		s.ErrorCode = errResp.Code
+	}
+}
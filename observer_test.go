@@ -0,0 +1,64 @@
+package gkpxc_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/gkpxctest"
+)
+
+type testObserver struct {
+	stats chan gkpxc.ActionStats
+	locks chan bool
+}
+
+func (o *testObserver) StartAction(_ context.Context, action string) func(gkpxc.ActionStats) {
+	return func(stats gkpxc.ActionStats) { o.stats <- stats }
+}
+
+func (o *testObserver) LockStateChanged(locked bool) { o.locks <- locked }
+
+func TestClient_Observer(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	observer := &testObserver{stats: make(chan gkpxc.ActionStats, 10), locks: make(chan bool, 1)}
+	client := gkpxctest.NewAssociatedClient(t, srv, gkpxc.WithObserver(observer))
+
+	_, err := client.GetLogins(context.Background(), gkpxc.GetLoginsRequest{URL: "https://example.com"})
+	if !gkpxc.IsErrorCode(err, gkpxc.ErrCodeNoLoginsFound) {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	srv.SetLocked(true, false)
+
+	if locked := <-observer.locks; !locked {
+		t.Fatalf("Unexpected lock state")
+	}
+
+	close(observer.stats)
+
+	var actions []string
+	for stats := range observer.stats {
+		actions = append(actions, stats.Action)
+
+		if stats.RequestBytes == 0 || stats.Duration <= 0 {
+			t.Fatalf("Unexpected stats %+v", stats)
+		}
+
+		if stats.Action != "get-logins" && (stats.ErrorClass != gkpxc.ErrorClassNone || stats.ResponseBytes == 0) {
+			t.Fatalf("Unexpected stats %+v", stats)
+		}
+
+		if stats.Action == "get-logins" && (stats.ErrorClass != gkpxc.ErrorClassKeepassXC || stats.ErrorCode != gkpxc.ErrCodeNoLoginsFound) {
+			t.Fatalf("Unexpected stats %+v", stats)
+		}
+	}
+
+	expectActions := []string{"change-public-keys", "associate", "test-associate", "get-logins"}
+	if !reflect.DeepEqual(actions, expectActions) {
+		t.Fatalf("Got actions %v, expected %v", actions, expectActions)
+	}
+}
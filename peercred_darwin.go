@@ -0,0 +1,13 @@
+package gkpxc
+
+import "golang.org/x/sys/unix"
+
+// peerUID returns uid of process on other side of unix socket using LOCAL_PEERCRED.
+func peerUID(fd int) (int, bool, error) {
+	cred, err := unix.GetsockoptXucred(fd, unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	if err != nil {
+		return 0, true, err
+	}
+
+	return int(cred.Uid), true, nil
+}
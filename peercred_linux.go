@@ -0,0 +1,13 @@
+package gkpxc
+
+import "golang.org/x/sys/unix"
+
+// peerUID returns uid of process on other side of unix socket using SO_PEERCRED.
+func peerUID(fd int) (int, bool, error) {
+	cred, err := unix.GetsockoptUcred(fd, unix.SOL_SOCKET, unix.SO_PEERCRED)
+	if err != nil {
+		return 0, true, err
+	}
+
+	return int(cred.Uid), true, nil
+}
@@ -0,0 +1,138 @@
+package gkpxc
+
+import (
+	"bytes"
+)
+
+// maxAbandoned limits count of remembered abandoned requests.
+const maxAbandoned = 16
+
+// pendingRequest is a request waiting for response.
+type pendingRequest struct {
+	action    string
+	requestID string
+	nonce     []byte          // expected response nonce
+	response  chan msgErrPair // receives exactly one response
+}
+
+type outgoingMessage struct {
+	Message
+	pending *pendingRequest
+}
+
+// addPending registers request before sending so response can be routed to it.
+func (c *Client) addPending(req Message) (*pendingRequest, error) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
+	if c.readErr != nil {
+		return nil, c.readErr
+	}
+
+	p := &pendingRequest{
+		action:    req.Action,
+		requestID: req.RequestID,
+		nonce:     incrementNonce(req.Nonce),
+		response:  make(chan msgErrPair, 1),
+	}
+
+	c.pending = append(c.pending, p)
+
+	return p, nil
+}
+
+// complete passes response to request if it's still pending.
+func (c *Client) complete(p *pendingRequest, resp msgErrPair) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
+	if i := indexOf(c.pending, p); i >= 0 {
+		c.pending = append(c.pending[:i], c.pending[i+1:]...)
+		p.response <- resp
+	}
+}
+
+// abandon marks request as not waited anymore (i.e. context canceled) so late response for it will be dropped.
+func (c *Client) abandon(p *pendingRequest) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
+	i := indexOf(c.pending, p)
+	if i < 0 {
+		return
+	}
+
+	c.pending = append(c.pending[:i], c.pending[i+1:]...)
+
+	c.abandoned = append(c.abandoned, p)
+	if len(c.abandoned) > maxAbandoned {
+		c.abandoned = c.abandoned[1:]
+	}
+}
+
+// route passes received message to request waiting for it. Messages are matched by nonce or request id.
+// Unencrypted error replies don't contain nonce so they're matched by action.
+// Late responses for abandoned requests are dropped. It returns false for unexpected message.
+func (c *Client) route(resp msgErrPair) bool {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
+	for _, match := range []func(p *pendingRequest) bool{
+		func(p *pendingRequest) bool { return exactMatch(p, resp.Message) },
+		func(p *pendingRequest) bool { return resp.Action == "" || p.action == resp.Action },
+	} {
+		if i := indexFunc(c.pending, match); i >= 0 {
+			p := c.pending[i]
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			p.response <- resp
+
+			return true
+		}
+
+		if i := indexFunc(c.abandoned, match); i >= 0 {
+			c.abandoned = append(c.abandoned[:i], c.abandoned[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// failPending passes permanent read error to all pending requests and following ones.
+func (c *Client) failPending(err error) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
+	c.readErr = err
+
+	for _, p := range c.pending {
+		p.response <- msgErrPair{error: err}
+	}
+
+	c.pending, c.abandoned = nil, nil
+}
+
+func exactMatch(p *pendingRequest, msg Message) bool {
+	switch {
+	case len(msg.Nonce) > 0:
+		return bytes.Equal(p.nonce, msg.Nonce)
+	case msg.RequestID != "":
+		return p.requestID == msg.RequestID
+	default:
+		return false
+	}
+}
+
+func indexOf(list []*pendingRequest, p *pendingRequest) int {
+	return indexFunc(list, func(item *pendingRequest) bool { return item == p })
+}
+
+func indexFunc(list []*pendingRequest, f func(p *pendingRequest) bool) int {
+	for i, p := range list {
+		if f(p) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,147 @@
+package gkpxc
+
+import (
+	"errors"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var errInvalidJSONString = errors.New("invalid JSON string")
+
+// secretBytes is a JSON string decoded to byte slice without intermediate string allocation
+// so caller is able to wipe it.
+type secretBytes []byte
+
+func (s *secretBytes) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*s = nil
+		return nil
+	}
+
+	unescaped, err := unescapeJSONString(data)
+	if err != nil {
+		return err
+	}
+
+	*s = unescaped
+
+	return nil
+}
+
+// unescapeJSONString decodes quoted JSON string. Invalid UTF-16 surrogates replaced with utf8.RuneError
+// like encoding/json does.
+func unescapeJSONString(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return nil, errInvalidJSONString
+	}
+
+	data = data[1 : len(data)-1]
+	ret := make([]byte, 0, len(data))
+
+	var runeBuf [utf8.UTFMax]byte
+
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '\\':
+		case c < ' ', c == '"':
+			wipe(ret)
+			return nil, errInvalidJSONString
+		default:
+			ret = append(ret, c)
+			i++
+			continue
+		}
+
+		if i+1 >= len(data) {
+			wipe(ret)
+			return nil, errInvalidJSONString
+		}
+
+		switch data[i+1] {
+		case '"', '\\', '/':
+			ret = append(ret, data[i+1])
+		case 'b':
+			ret = append(ret, '\b')
+		case 'f':
+			ret = append(ret, '\f')
+		case 'n':
+			ret = append(ret, '\n')
+		case 'r':
+			ret = append(ret, '\r')
+		case 't':
+			ret = append(ret, '\t')
+		case 'u':
+			r, ok := parseHex4(data[i+2:])
+			if !ok {
+				wipe(ret)
+				return nil, errInvalidJSONString
+			}
+
+			i += 4
+
+			if utf16.IsSurrogate(r) {
+				r2, ok := rune(0), false
+				if i+7 < len(data) && data[i+2] == '\\' && data[i+3] == 'u' {
+					r2, ok = parseHex4(data[i+4:])
+				}
+
+				if dec := utf16.DecodeRune(r, r2); ok && dec != utf8.RuneError {
+					r = dec
+					i += 6
+				} else {
+					r = utf8.RuneError
+				}
+			}
+
+			n := utf8.EncodeRune(runeBuf[:], r)
+			ret = append(ret, runeBuf[:n]...)
+			wipe(runeBuf[:])
+		default:
+			wipe(ret)
+			return nil, errInvalidJSONString
+		}
+
+		i += 2
+	}
+
+	return ret, nil
+}
+
+func parseHex4(b []byte) (rune, bool) {
+	if len(b) < 4 {
+		return 0, false
+	}
+
+	var r rune
+
+	for _, c := range b[:4] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, false
+		}
+
+		r = r*16 + rune(c)
+	}
+
+	return r, true
+}
+
+// wipe zeroes secret data.
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+func wipeKey(key *[KeySize]byte) {
+	if key != nil {
+		wipe(key[:])
+	}
+}
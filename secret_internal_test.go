@@ -0,0 +1,101 @@
+package gkpxc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestUnescapeJSONString(t *testing.T) {
+	for _, s := range []string{
+		``,
+		`simple`,
+		`quote " and backslash \ and slash /`,
+		"controls \b\f\n\r\t\x01",
+		`unicode пароль 密码 🔑`,
+		"invalid \xff utf8",
+	} {
+		encoded, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal("Marshal", err)
+		}
+
+		unescaped, err := unescapeJSONString(encoded)
+		if err != nil {
+			t.Fatalf("Unexpected error %s for %s", err, encoded)
+		}
+
+		var expect string
+		if err = json.Unmarshal(encoded, &expect); err != nil {
+			t.Fatal("Unmarshal", err)
+		}
+
+		if string(unescaped) != expect {
+			t.Fatalf("Got %q, expected %q", unescaped, expect)
+		}
+	}
+
+	for _, s := range []string{`"🔑"`, `"\uD83D"`, `"\udd11\ud83d x"`, `"A\/"`} {
+		unescaped, err := unescapeJSONString([]byte(s))
+		if err != nil {
+			t.Fatalf("Unexpected error %s for %s", err, s)
+		}
+
+		var expect string
+		if err = json.Unmarshal([]byte(s), &expect); err != nil {
+			t.Fatal("Unmarshal", err)
+		}
+
+		if string(unescaped) != expect {
+			t.Fatalf("Got %q, expected %q for %s", unescaped, expect, s)
+		}
+	}
+
+	for _, s := range []string{`"`, `abc`, `"\"`, `"\x"`, `"\u12"`, `"a"b"`, "\"\n\""} {
+		if _, err := unescapeJSONString([]byte(s)); !errors.Is(err, errInvalidJSONString) {
+			t.Fatalf("Unexpected error %v for %s", err, s)
+		}
+	}
+}
+
+func TestClient_Close_wipes_keys(t *testing.T) {
+	cc, sc := net.Pipe()
+	go func() {
+		var req Message
+		json.NewDecoder(sc).Decode(&req)
+		json.NewEncoder(sc).Encode(Message{
+			Action:    "change-public-keys",
+			PublicKey: bytes.Repeat([]byte{1}, KeySize),
+			Nonce:     incrementNonce(req.Nonce),
+		})
+		sc.Close()
+	}()
+
+	c, err := NewClient(context.Background(), WithConn(cc))
+	if err != nil {
+		t.Fatalf("Got error %s, expected nil", err)
+	}
+
+	privateKey, sharedKey := c.privateKey, c.sharedKey
+
+	if *privateKey != ([KeySize]byte{}) {
+		t.Fatalf("Private key must be wiped after handshake")
+	}
+
+	if *sharedKey == ([KeySize]byte{}) {
+		t.Fatalf("Shared key must be computed")
+	}
+
+	c.Close()
+
+	if *sharedKey != ([KeySize]byte{}) {
+		t.Fatalf("Shared key must be wiped on close")
+	}
+
+	if _, err = c.seal([]byte("test"), new([NonceSize]byte)); !errors.Is(err, ErrClosing) {
+		t.Fatalf("Expected ErrClosing, got %v", err)
+	}
+}
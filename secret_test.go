@@ -0,0 +1,28 @@
+package gkpxc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/gkpxctest"
+)
+
+func TestClient_SecretBytes(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	srv.AddEntry(gkpxctest.Entry{URL: "https://example.com", Login: "user", Password: "pa\"ss\u2028"})
+
+	client := gkpxctest.NewAssociatedClient(t, srv, gkpxc.WithSecretBytes())
+
+	logins, err := client.GetLogins(context.Background(), gkpxc.GetLoginsRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatal("GetLogins", err)
+	}
+
+	if len(logins.Entries) != 1 || logins.Entries[0].Password != "" ||
+		string(logins.Entries[0].PasswordBytes) != "pa\"ss\u2028" || logins.Entries[0].Login != "user" {
+		t.Fatalf("Unexpected entries %+v", logins.Entries)
+	}
+}
@@ -0,0 +1,204 @@
+// Package secretstore provides simple key-value storage for secrets on top of KeepassXC logins.
+package secretstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/xakep666/gkpxc"
+)
+
+// Scheme is a scheme of synthetic URLs used for entries.
+const Scheme = "gkpxc"
+
+var (
+	// ErrNotFound returned if no secret stored for key.
+	ErrNotFound = errors.New("secret not found")
+
+	// ErrAmbiguous returned if multiple entries found for key.
+	ErrAmbiguous = errors.New("multiple entries found for key")
+)
+
+// Meta is an arbitrary secret metadata.
+type Meta map[string]string
+
+// Secret is a stored secret.
+type Secret struct {
+	Key   string
+	Value []byte
+	Meta  Meta
+
+	// UUID is KeepassXC entry UUID.
+	UUID string
+}
+
+// envelope is stored in entry password because browser protocol doesn't allow to set other entry fields.
+type envelope struct {
+	Value []byte `json:"value"`
+	Meta  Meta   `json:"meta,omitempty"`
+}
+
+// Store maps keys to entries with URL "gkpxc://<namespace>/<key>" and login "<key>".
+// Store is not safe for concurrent use as underlying client.
+type Store struct {
+	Client *gkpxc.Client
+
+	// Namespace separates secrets of different applications. Must be valid host name.
+	Namespace string
+
+	// Group is a group path for new entries. Created if not exists. Default is "gkpxc/<namespace>".
+	Group string
+
+	group *gkpxc.DatabaseGroup
+}
+
+// Get returns secret by key. ErrNotFound returned if secret not exists.
+func (s *Store) Get(ctx context.Context, key string) (Secret, error) {
+	entries, err := s.entries(ctx, s.keyURL(key))
+	if err != nil {
+		return Secret{}, err
+	}
+
+	var found []gkpxc.LoginEntry
+	for _, entry := range entries {
+		if entry.Login == key {
+			found = append(found, entry)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return Secret{}, ErrNotFound
+	case 1:
+		return decodeSecret(found[0])
+	default:
+		return Secret{}, fmt.Errorf("%s: %w", key, ErrAmbiguous)
+	}
+}
+
+// Put creates or updates secret.
+func (s *Store) Put(ctx context.Context, key string, value []byte, meta Meta) error {
+	if key == "" {
+		return fmt.Errorf("empty key")
+	}
+
+	existing, err := s.Get(ctx, key)
+	switch {
+	case errors.Is(err, nil), errors.Is(err, ErrNotFound):
+	default:
+		return err
+	}
+
+	group, err := s.getOrCreateGroup(ctx)
+	if err != nil {
+		return err
+	}
+
+	serialized, err := json.Marshal(envelope{Value: value, Meta: meta})
+	if err != nil {
+		return fmt.Errorf("serialize secret: %w", err)
+	}
+
+	return s.Client.SetLogin(ctx, gkpxc.SetLoginRequest{
+		URL:       s.keyURL(key),
+		Login:     key,
+		Password:  string(serialized),
+		Group:     group.Name,
+		GroupUUID: group.UUID,
+		UUID:      existing.UUID,
+	})
+}
+
+// Delete deletes secret. ErrNotFound returned if secret not exists.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	existing, err := s.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return s.Client.DeleteEntry(ctx, gkpxc.DeleteEntryRequest{UUID: existing.UUID})
+}
+
+// List returns sorted keys with given prefix.
+func (s *Store) List(ctx context.Context, prefix string) ([]string, error) {
+	entries, err := s.entries(ctx, s.keyURL(""))
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, entry := range entries {
+		if entry.Login != "" && strings.HasPrefix(entry.Login, prefix) {
+			keys = append(keys, entry.Login)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (s *Store) entries(ctx context.Context, lookupURL string) ([]gkpxc.LoginEntry, error) {
+	logins, err := s.Client.GetLogins(ctx, gkpxc.GetLoginsRequest{URL: lookupURL})
+	switch {
+	case errors.Is(err, nil):
+		return logins.Entries, nil
+	case gkpxc.IsErrorCode(err, gkpxc.ErrCodeNoLoginsFound):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("get logins: %w", err)
+	}
+}
+
+func (s *Store) keyURL(key string) string {
+	return (&url.URL{Scheme: Scheme, Host: s.Namespace, Path: "/" + key}).String()
+}
+
+func (s *Store) getOrCreateGroup(ctx context.Context) (gkpxc.DatabaseGroup, error) {
+	if s.group != nil {
+		return *s.group, nil
+	}
+
+	path := s.Group
+	if path == "" {
+		path = Scheme + "/" + s.Namespace
+	}
+
+	groups, err := s.Client.GetDatabaseGroups(ctx)
+	if err != nil {
+		return gkpxc.DatabaseGroup{}, fmt.Errorf("get database groups failed: %w", err)
+	}
+
+	group, ok := groups.FindGroup(path)
+	if !ok {
+		created, err := s.Client.CreateNewGroup(ctx, gkpxc.CreateNewGroupRequest{Name: path})
+		if err != nil {
+			return gkpxc.DatabaseGroup{}, fmt.Errorf("create group failed: %w", err)
+		}
+
+		group = gkpxc.DatabaseGroup{Name: created.Name, UUID: created.UUID}
+	}
+
+	s.group = &group
+
+	return group, nil
+}
+
+func decodeSecret(entry gkpxc.LoginEntry) (Secret, error) {
+	var env envelope
+	if err := json.Unmarshal([]byte(entry.Password), &env); err != nil {
+		return Secret{}, fmt.Errorf("%s: decode secret: %w", entry.Login, err)
+	}
+
+	return Secret{
+		Key:   entry.Login,
+		Value: env.Value,
+		Meta:  env.Meta,
+		UUID:  entry.UUID,
+	}, nil
+}
@@ -0,0 +1,40 @@
+package secretstore
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xakep666/gkpxc"
+)
+
+func TestStore_keyURL(t *testing.T) {
+	s := Store{Namespace: "myapp"}
+
+	if u := s.keyURL("db/password"); u != "gkpxc://myapp/db/password" {
+		t.Fatalf("Unexpected url %s", u)
+	}
+
+	if u := s.keyURL("with space"); u != "gkpxc://myapp/with%20space" {
+		t.Fatalf("Unexpected url %s", u)
+	}
+}
+
+func TestDecodeSecret(t *testing.T) {
+	secret, err := decodeSecret(gkpxc.LoginEntry{
+		UUID:     "entry-uuid",
+		Login:    "db/password",
+		Password: `{"value":"c2VjcmV0","meta":{"owner":"me"}}`,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if secret.Key != "db/password" || !bytes.Equal(secret.Value, []byte("secret")) ||
+		secret.Meta["owner"] != "me" || secret.UUID != "entry-uuid" {
+		t.Fatalf("Unexpected secret %+v", secret)
+	}
+
+	if _, err = decodeSecret(gkpxc.LoginEntry{Password: "plain"}); err == nil {
+		t.Fatal("Expected error for non-envelope password")
+	}
+}
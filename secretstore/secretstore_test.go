@@ -0,0 +1,154 @@
+package secretstore_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/xakep666/gkpxc/gkpxctest"
+	"github.com/xakep666/gkpxc/secretstore"
+)
+
+func newStore(t *testing.T, srv *gkpxctest.Server, namespace string) *secretstore.Store {
+	return &secretstore.Store{Client: gkpxctest.NewAssociatedClient(t, srv), Namespace: namespace}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	srv := gkpxctest.NewTestServer(t)
+	store := newStore(t, srv, "myapp")
+
+	if _, err := store.Get(ctx, "db/password"); !errors.Is(err, secretstore.ErrNotFound) {
+		t.Fatalf("Unexpected error %v, expected ErrNotFound", err)
+	}
+
+	if err := store.Delete(ctx, "db/password"); !errors.Is(err, secretstore.ErrNotFound) {
+		t.Fatalf("Unexpected error %v, expected ErrNotFound", err)
+	}
+
+	keys, err := store.List(ctx, "")
+	if err != nil {
+		t.Fatal("List", err)
+	}
+
+	if len(keys) != 0 {
+		t.Fatalf("Unexpected keys %v", keys)
+	}
+
+	if err = store.Put(ctx, "db/password", []byte("secret"), secretstore.Meta{"owner": "me"}); err != nil {
+		t.Fatal("Put", err)
+	}
+
+	if err = store.Put(ctx, "api/token", []byte("token"), nil); err != nil {
+		t.Fatal("Put", err)
+	}
+
+	groups := srv.Groups()
+	if len(groups.Children) != 1 || groups.Children[0].Name != "gkpxc" ||
+		len(groups.Children[0].Children) != 1 || groups.Children[0].Children[0].Name != "myapp" {
+		t.Fatalf("Unexpected groups %+v", groups)
+	}
+
+	for _, entry := range srv.Entries() {
+		if entry.GroupUUID != groups.Children[0].Children[0].UUID {
+			t.Fatalf("Entry %+v created outside of namespace group", entry)
+		}
+	}
+
+	secret, err := store.Get(ctx, "db/password")
+	if err != nil {
+		t.Fatal("Get", err)
+	}
+
+	if !bytes.Equal(secret.Value, []byte("secret")) || secret.Meta["owner"] != "me" || secret.UUID == "" {
+		t.Fatalf("Unexpected secret %+v", secret)
+	}
+
+	keys, err = store.List(ctx, "")
+	if err != nil {
+		t.Fatal("List", err)
+	}
+
+	if !reflect.DeepEqual(keys, []string{"api/token", "db/password"}) {
+		t.Fatalf("Unexpected keys %v", keys)
+	}
+
+	keys, err = store.List(ctx, "db/")
+	if err != nil {
+		t.Fatal("List", err)
+	}
+
+	if !reflect.DeepEqual(keys, []string{"db/password"}) {
+		t.Fatalf("Unexpected keys %v", keys)
+	}
+
+	if err = store.Delete(ctx, "db/password"); err != nil {
+		t.Fatal("Delete", err)
+	}
+
+	if _, err = store.Get(ctx, "db/password"); !errors.Is(err, secretstore.ErrNotFound) {
+		t.Fatalf("Unexpected error %v, expected ErrNotFound", err)
+	}
+
+	if _, err = store.Get(ctx, "api/token"); err != nil {
+		t.Fatal("Get", err)
+	}
+}
+
+func TestStore_Overwrite(t *testing.T) {
+	ctx := context.Background()
+	srv := gkpxctest.NewTestServer(t)
+	store := newStore(t, srv, "myapp")
+
+	if err := store.Put(ctx, "db/password", []byte("old"), secretstore.Meta{"version": "1"}); err != nil {
+		t.Fatal("Put", err)
+	}
+
+	old, err := store.Get(ctx, "db/password")
+	if err != nil {
+		t.Fatal("Get", err)
+	}
+
+	if err = store.Put(ctx, "db/password", []byte("new"), secretstore.Meta{"version": "2"}); err != nil {
+		t.Fatal("Put", err)
+	}
+
+	secret, err := store.Get(ctx, "db/password")
+	if err != nil {
+		t.Fatal("Get", err)
+	}
+
+	if !bytes.Equal(secret.Value, []byte("new")) || secret.Meta["version"] != "2" || secret.UUID != old.UUID {
+		t.Fatalf("Unexpected secret %+v, previous %+v", secret, old)
+	}
+
+	if entries := srv.Entries(); len(entries) != 1 {
+		t.Fatalf("Expected one entry, got %+v", entries)
+	}
+}
+
+func TestStore_Namespaces(t *testing.T) {
+	ctx := context.Background()
+	srv := gkpxctest.NewTestServer(t)
+	store := newStore(t, srv, "myapp")
+	other := newStore(t, srv, "otherapp")
+
+	if err := store.Put(ctx, "key", []byte("secret"), nil); err != nil {
+		t.Fatal("Put", err)
+	}
+
+	if _, err := other.Get(ctx, "key"); !errors.Is(err, secretstore.ErrNotFound) {
+		t.Fatalf("Unexpected error %v, expected ErrNotFound", err)
+	}
+
+	keys, err := other.List(ctx, "")
+	if err != nil {
+		t.Fatal("List", err)
+	}
+
+	if len(keys) != 0 {
+		t.Fatalf("Unexpected keys %v", keys)
+	}
+}
@@ -0,0 +1,96 @@
+package gkpxc
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Redacted replaces secret values in TraceEvent.
+const Redacted = "<redacted>"
+
+// redactedFields are JSON fields containing secrets. Public keys redacted too because they identify association.
+var redactedFields = map[string]bool{
+	"password":     true,
+	"totp":         true,
+	"key":          true,
+	"idKey":        true,
+	"publicKey":    true,
+	"stringFields": true,
+}
+
+// TraceEvent describes single exchange with KeepassXC.
+type TraceEvent struct {
+	Action string
+
+	// Request is a plaintext request JSON (before encryption).
+	Request json.RawMessage
+
+	// Response is a plaintext response JSON (after decryption). It's empty if response was not received or decrypted.
+	Response json.RawMessage
+
+	Start    time.Time
+	Duration time.Duration
+
+	// NonceValid is set if response was received and its nonce matched request one.
+	NonceValid bool
+
+	// ErrorCode is a KeepassXC error code if it responded with error.
+	ErrorCode int
+
+	Err error
+}
+
+// finish fills event result from exchange error.
+func (e *TraceEvent) finish(err error) {
+	e.Duration = time.Since(e.Start)
+	e.Err = err
+
+	var errResp *ErrorResponse
+	if errors.As(err, &errResp) {
+		e.ErrorCode = errResp.Code
+	}
+}
+
+func (c *Client) traceJSON(data []byte) json.RawMessage {
+	if c.traceSecrets {
+		return append(json.RawMessage(nil), data...)
+	}
+
+	return redactJSON(data)
+}
+
+// redactJSON replaces secret fields values with Redacted.
+func redactJSON(data []byte) json.RawMessage {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil
+	}
+
+	ret, err := json.Marshal(redact(v))
+	if err != nil {
+		return nil
+	}
+
+	return ret
+}
+
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for name, value := range v {
+			if redactedFields[name] {
+				v[name] = Redacted
+				continue
+			}
+
+			v[name] = redact(value)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,36 @@
+package gkpxc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRedactJSON(t *testing.T) {
+	redacted := redactJSON([]byte(`{
+		"action": "get-logins",
+		"keys": [{"id": "test", "key": "a2V5"}],
+		"entries": [{"login": "user", "password": "secret", "stringFields": [{"KPH: token": "secret"}]}],
+		"totp": "123456"
+	}`))
+
+	var actual, expect interface{}
+	if err := json.Unmarshal(redacted, &actual); err != nil {
+		t.Fatal("Unmarshal", err)
+	}
+
+	_ = json.Unmarshal([]byte(`{
+		"action": "get-logins",
+		"keys": [{"id": "test", "key": "<redacted>"}],
+		"entries": [{"login": "user", "password": "<redacted>", "stringFields": "<redacted>"}],
+		"totp": "<redacted>"
+	}`), &expect)
+
+	if !reflect.DeepEqual(actual, expect) {
+		t.Fatalf("Got %s", redacted)
+	}
+
+	if redactJSON([]byte(`{invalid`)) != nil {
+		t.Fatalf("Invalid JSON must not be traced")
+	}
+}
@@ -0,0 +1,58 @@
+package gkpxc_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/gkpxctest"
+)
+
+func TestClient_Tracer(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	srv.AddEntry(gkpxctest.Entry{URL: "https://example.com", Login: "user", Password: "secret"})
+
+	ctx := context.Background()
+
+	var events []gkpxc.TraceEvent
+
+	client := gkpxctest.NewAssociatedClient(t, srv, gkpxc.WithTracer(func(e gkpxc.TraceEvent) {
+		events = append(events, e)
+	}))
+
+	if _, err := client.GetLogins(ctx, gkpxc.GetLoginsRequest{URL: "https://example.com"}); err != nil {
+		t.Fatal("GetLogins", err)
+	}
+
+	_, err := client.GetLogins(ctx, gkpxc.GetLoginsRequest{URL: "https://other.com"})
+	if !gkpxc.IsErrorCode(err, gkpxc.ErrCodeNoLoginsFound) {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	var actions []string
+	for _, e := range events {
+		actions = append(actions, e.Action)
+
+		if strings.Contains(string(e.Request)+string(e.Response), "secret") {
+			t.Fatalf("Secret not redacted in %+v", e)
+		}
+	}
+
+	expectActions := []string{"change-public-keys", "associate", "test-associate", "get-logins", "test-associate", "get-logins"}
+	if !reflect.DeepEqual(actions, expectActions) {
+		t.Fatalf("Got actions %v, expected %v", actions, expectActions)
+	}
+
+	success, failed := events[3], events[5]
+	if !success.NonceValid || success.Err != nil || !strings.Contains(string(success.Response), `"login":"user"`) {
+		t.Fatalf("Unexpected event %+v", success)
+	}
+
+	if failed.NonceValid || failed.ErrorCode != gkpxc.ErrCodeNoLoginsFound || failed.Response != nil {
+		t.Fatalf("Unexpected event %+v", failed)
+	}
+}
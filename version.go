@@ -0,0 +1,112 @@
+package gkpxc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedByServer returned if connected KeepassXC is too old to perform requested action.
+var ErrUnsupportedByServer = fmt.Errorf("unsupported by server")
+
+// Version is a KeepassXC version like "2.7.4". Pre-release suffix ("-snapshot", "-beta1") is kept but ignored in comparison.
+// Zero value means unknown version.
+type Version struct {
+	Major, Minor, Patch int
+	Suffix              string
+}
+
+// ParseVersion parses KeepassXC version.
+func ParseVersion(s string) (Version, error) {
+	var v Version
+
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+ "); i >= 0 {
+		s, v.Suffix = s[:i], s[i:]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+
+	for i, dst := range []*int{&v.Major, &v.Minor, &v.Patch}[:len(parts)] {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+
+		*dst = n
+	}
+
+	return v, nil
+}
+
+// IsZero reports whether version is unknown.
+func (v Version) IsZero() bool { return v == Version{} }
+
+// AtLeast reports whether version is same or newer than major.minor.patch.
+func (v Version) AtLeast(major, minor, patch int) bool {
+	switch {
+	case v.Major != major:
+		return v.Major > major
+	case v.Minor != minor:
+		return v.Minor > minor
+	default:
+		return v.Patch >= patch
+	}
+}
+
+func (v Version) String() string {
+	if v.IsZero() {
+		return ""
+	}
+
+	return fmt.Sprintf("%d.%d.%d%s", v.Major, v.Minor, v.Patch, v.Suffix)
+}
+
+// Capabilities describes actions and fields supported by connected KeepassXC. They're determined by version
+// reported during handshake. If version is unknown everything is considered supported.
+type Capabilities struct {
+	Version Version
+
+	// DeleteEntry is set if "delete-entry" action supported (2.7.0).
+	DeleteEntry bool
+
+	// LoginTOTP is set if "get-logins" returns current TOTP in LoginEntry.TOTP (2.6.0).
+	LoginTOTP bool
+
+	// RequestID is set if KeepassXC echoes "requestID" field so responses can be matched with requests (2.7.5).
+	RequestID bool
+
+	// GetDatabaseEntries is set if "get-database-entries" action supported (2.7.7).
+	GetDatabaseEntries bool
+
+	// Passkeys is set if "passkeys-register" and "passkeys-get" actions supported (2.7.7).
+	Passkeys bool
+}
+
+// capabilitiesOf returns capabilities of KeepassXC version.
+func capabilitiesOf(v Version) Capabilities {
+	since := func(major, minor, patch int) bool {
+		return v.IsZero() || v.AtLeast(major, minor, patch)
+	}
+
+	return Capabilities{
+		Version:            v,
+		DeleteEntry:        since(2, 7, 0),
+		LoginTOTP:          since(2, 6, 0),
+		RequestID:          since(2, 7, 5),
+		GetDatabaseEntries: since(2, 7, 7),
+		Passkeys:           since(2, 7, 7),
+	}
+}
+
+// checkSupported returns ErrUnsupportedByServer if action is not supported.
+func (c *Client) checkSupported(action string, supported bool) error {
+	if supported {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s requires newer KeepassXC than %s", ErrUnsupportedByServer, action, c.capabilities.Version)
+}
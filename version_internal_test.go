@@ -0,0 +1,71 @@
+package gkpxc
+
+import (
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	cases := []struct {
+		raw    string
+		expect Version
+		err    bool
+	}{
+		{raw: "2.7.4", expect: Version{Major: 2, Minor: 7, Patch: 4}},
+		{raw: "2.8.0-snapshot", expect: Version{Major: 2, Minor: 8, Suffix: "-snapshot"}},
+		{raw: "2.6", expect: Version{Major: 2, Minor: 6}},
+		{raw: "", err: true},
+		{raw: "2.x.1", err: true},
+		{raw: "1.2.3.4", err: true},
+	}
+
+	for _, c := range cases {
+		v, err := ParseVersion(c.raw)
+		if (err != nil) != c.err || v != c.expect {
+			t.Fatalf("ParseVersion(%q) = %+v, %v; expected %+v", c.raw, v, err, c.expect)
+		}
+	}
+
+	if v, _ := ParseVersion("2.8.0-snapshot"); v.String() != "2.8.0-snapshot" {
+		t.Fatalf("Unexpected string %s", v)
+	}
+}
+
+func TestVersion_AtLeast(t *testing.T) {
+	v := Version{Major: 2, Minor: 7, Patch: 4}
+
+	for _, newer := range [][3]int{{2, 7, 5}, {2, 8, 0}, {3, 0, 0}} {
+		if v.AtLeast(newer[0], newer[1], newer[2]) {
+			t.Fatalf("%s is not at least %v", v, newer)
+		}
+	}
+
+	for _, older := range [][3]int{{2, 7, 4}, {2, 7, 0}, {2, 6, 9}, {1, 9, 9}} {
+		if !v.AtLeast(older[0], older[1], older[2]) {
+			t.Fatalf("%s is at least %v", v, older)
+		}
+	}
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+	if caps := capabilitiesOf(Version{Major: 2, Minor: 6, Patch: 6}); caps.DeleteEntry || caps.RequestID || !caps.LoginTOTP {
+		t.Fatalf("Unexpected capabilities %+v", caps)
+	}
+
+	if caps := capabilitiesOf(Version{Major: 2, Minor: 7, Patch: 7}); !caps.DeleteEntry || !caps.Passkeys || !caps.GetDatabaseEntries {
+		t.Fatalf("Unexpected capabilities %+v", caps)
+	}
+
+	if caps := capabilitiesOf(Version{}); !caps.DeleteEntry || !caps.Passkeys || !caps.RequestID {
+		t.Fatalf("Unknown version must support everything, got %+v", caps)
+	}
+}
+
+func TestGetDatabaseHashResponse_ParsedVersion(t *testing.T) {
+	if v := (GetDatabaseHashResponse{Version: "2.7.4"}).ParsedVersion(); v != (Version{Major: 2, Minor: 7, Patch: 4}) {
+		t.Fatalf("Unexpected version %s", v)
+	}
+
+	if v := (GetDatabaseHashResponse{Version: "unknown"}).ParsedVersion(); !v.IsZero() {
+		t.Fatalf("Malformed version must be parsed as zero, got %s", v)
+	}
+}
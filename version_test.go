@@ -0,0 +1,73 @@
+package gkpxc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/xakep666/gkpxc"
+	"github.com/xakep666/gkpxc/gkpxctest"
+)
+
+func TestClient_Capabilities(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	srv.SetVersion("2.6.6")
+	uuid := srv.AddEntry(gkpxctest.Entry{URL: "https://example.com", Login: "user", Password: "pass", TOTP: "123456"})
+
+	ctx := context.Background()
+	client := gkpxctest.NewAssociatedClient(t, srv)
+
+	caps := client.Capabilities()
+	if caps.Version.String() != "2.6.6" || caps.DeleteEntry || !caps.LoginTOTP {
+		t.Fatalf("Unexpected capabilities %+v", caps)
+	}
+
+	hash, err := client.GetDatabaseHash(ctx, false)
+	if err != nil {
+		t.Fatal("GetDatabaseHash", err)
+	}
+
+	if !hash.ParsedVersion().AtLeast(2, 6, 6) || hash.ParsedVersion().AtLeast(2, 6, 7) {
+		t.Fatalf("Unexpected version %s", hash.Version)
+	}
+
+	logins, err := client.GetLogins(ctx, gkpxc.GetLoginsRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatal("GetLogins", err)
+	}
+
+	if len(logins.Entries) != 1 || logins.Entries[0].TOTP != "123456" {
+		t.Fatalf("Unexpected entries %+v", logins.Entries)
+	}
+
+	if err = client.DeleteEntry(ctx, gkpxc.DeleteEntryRequest{UUID: uuid}); !errors.Is(err, gkpxc.ErrUnsupportedByServer) {
+		t.Fatalf("Unexpected error %v, expected ErrUnsupportedByServer", err)
+	}
+
+	if len(srv.Entries()) != 1 {
+		t.Fatalf("Entry deleted")
+	}
+}
+
+func TestClient_DeleteEntry_incorrectAction(t *testing.T) {
+	srv := gkpxctest.NewServer()
+	defer srv.Close()
+
+	uuid := srv.AddEntry(gkpxctest.Entry{URL: "https://example.com", Login: "user", Password: "pass"})
+
+	// server reports version supporting deletion but doesn't know action, i.e. custom build
+	client := gkpxctest.NewAssociatedClient(t, srv, gkpxc.WithInterceptor(func(ctx context.Context, action string, req, resp interface{}, next gkpxc.Invoker) error {
+		if action == "delete-entry" {
+			return &gkpxc.ErrorResponse{Text: "Incorrect action", Code: gkpxc.ErrCodeIncorrectAction}
+		}
+
+		return next(ctx, action, req, resp)
+	}))
+
+	err := client.DeleteEntry(context.Background(), gkpxc.DeleteEntryRequest{UUID: uuid})
+	if !errors.Is(err, gkpxc.ErrUnsupportedByServer) || !gkpxc.IsErrorCode(err, gkpxc.ErrCodeIncorrectAction) {
+		t.Fatalf("Unexpected error %v, expected ErrUnsupportedByServer caused by incorrect action", err)
+	}
+}